@@ -0,0 +1,127 @@
+package sqwiggle
+
+import (
+	"context"
+	"iter"
+)
+
+// API is the interface implemented by Client, covering every operation
+// of the Sqwiggle API. Code that accepts an API rather than a *Client can
+// be tested with a fake implementation, such as sqwiggletest.Mock.
+// The operations are grouped by resource in the interfaces below, so
+// that code only needing part of the API can depend on less.
+type API interface {
+	MessagesAPI
+	StreamsAPI
+	UsersAPI
+	OrganizationsAPI
+	InfoAPI
+	ConversationsAPI
+	InvitesAPI
+	AttachmentsAPI
+}
+
+// Client implements API.
+var _ API = (*Client)(nil)
+
+// MessagesAPI covers the operations on messages.
+type MessagesAPI interface {
+	ListMessages(page, limit int) ([]Message, error)
+	ListMessagesContext(ctx context.Context, page, limit int) ([]Message, error)
+	AllMessages(ctx context.Context, opts *PageOptions) iter.Seq2[Message, error]
+	ListMessagesWithOptions(page, limit int, options *ListMessagesOptions) ([]Message, error)
+	ListMessagesWithOptionsContext(ctx context.Context, page, limit int, options *ListMessagesOptions) ([]Message, error)
+	AllMessagesWithOptions(ctx context.Context, opts *PageOptions, options *ListMessagesOptions) iter.Seq2[Message, error]
+	ListStreamMessages(streamID, page, limit int) ([]Message, error)
+	ListStreamMessagesContext(ctx context.Context, streamID, page, limit int) ([]Message, error)
+	GetMessage(id int) (Message, error)
+	GetMessageContext(ctx context.Context, id int) (Message, error)
+	PostMessage(streamID int, text string, options *PostMessageOptions) (Message, error)
+	PostMessageContext(ctx context.Context, streamID int, text string, options *PostMessageOptions) (Message, error)
+	UpdateMessage(id int, text string) (Message, error)
+	UpdateMessageContext(ctx context.Context, id int, text string) (Message, error)
+	DeleteMessage(id int) error
+	DeleteMessageContext(ctx context.Context, id int) error
+}
+
+// StreamsAPI covers the operations on streams.
+type StreamsAPI interface {
+	ListStreams(page, limit int) ([]Stream, error)
+	ListStreamsContext(ctx context.Context, page, limit int) ([]Stream, error)
+	AllStreams(ctx context.Context, opts *PageOptions) iter.Seq2[Stream, error]
+	GetStream(id int) (Stream, error)
+	GetStreamContext(ctx context.Context, id int) (Stream, error)
+	PostStream(name string) (Stream, error)
+	PostStreamContext(ctx context.Context, name string) (Stream, error)
+	UpdateStream(id int, name string) (Stream, error)
+	UpdateStreamContext(ctx context.Context, id int, name string) (Stream, error)
+	DeleteStream(id int) error
+	DeleteStreamContext(ctx context.Context, id int) error
+}
+
+// UsersAPI covers the operations on users.
+type UsersAPI interface {
+	ListUsers(page, limit int) ([]User, error)
+	ListUsersContext(ctx context.Context, page, limit int) ([]User, error)
+	AllUsers(ctx context.Context, opts *PageOptions) iter.Seq2[User, error]
+	GetUser(id int) (User, error)
+	GetUserContext(ctx context.Context, id int) (User, error)
+	UpdateUser(id int, options *UpdateUserOptions) (User, error)
+	UpdateUserContext(ctx context.Context, id int, options *UpdateUserOptions) (User, error)
+}
+
+// OrganizationsAPI covers the operations on organizations.
+type OrganizationsAPI interface {
+	ListOrganizations(page, limit int) ([]Organization, error)
+	ListOrganizationsContext(ctx context.Context, page, limit int) ([]Organization, error)
+	AllOrganizations(ctx context.Context, opts *PageOptions) iter.Seq2[Organization, error]
+	GetOrganization(id int) (Organization, error)
+	GetOrganizationContext(ctx context.Context, id int) (Organization, error)
+	UpdateOrganization(id int, options *UpdateOrganizationOptions) (Organization, error)
+	UpdateOrganizationContext(ctx context.Context, id int, options *UpdateOrganizationOptions) (Organization, error)
+}
+
+// InfoAPI covers the operations on the /info endpoint.
+type InfoAPI interface {
+	GetInfo() ([]byte, error)
+	GetInfoContext(ctx context.Context) ([]byte, error)
+	GetInfoTyped() (Info, error)
+	GetInfoTypedContext(ctx context.Context) (Info, error)
+}
+
+// ConversationsAPI covers the operations on conversations.
+type ConversationsAPI interface {
+	ListConversations(page, limit int) ([]Conversation, error)
+	ListConversationsContext(ctx context.Context, page, limit int) ([]Conversation, error)
+	AllConversations(ctx context.Context, opts *PageOptions) iter.Seq2[Conversation, error]
+	GetConversation(id int) (Conversation, error)
+	GetConversationContext(ctx context.Context, id int) (Conversation, error)
+}
+
+// InvitesAPI covers the operations on invites.
+type InvitesAPI interface {
+	ListInvites(page, limit int) ([]Invite, error)
+	ListInvitesContext(ctx context.Context, page, limit int) ([]Invite, error)
+	AllInvites(ctx context.Context, opts *PageOptions) iter.Seq2[Invite, error]
+	GetInvite(id int) (Invite, error)
+	GetInviteContext(ctx context.Context, id int) (Invite, error)
+	PostInvite(email string) (Invite, error)
+	PostInviteContext(ctx context.Context, email string) (Invite, error)
+	DeleteInvite(id int) error
+	DeleteInviteContext(ctx context.Context, id int) error
+}
+
+// AttachmentsAPI covers the operations on attachments.
+type AttachmentsAPI interface {
+	ListAttachments(page, limit int) ([]Attachment, error)
+	ListAttachmentsContext(ctx context.Context, page, limit int) ([]Attachment, error)
+	AllAttachments(ctx context.Context, opts *PageOptions) iter.Seq2[Attachment, error]
+	GetAttachment(id int) (Attachment, error)
+	GetAttachmentContext(ctx context.Context, id int) (Attachment, error)
+	PostAttachment(name string) (Attachment, error)
+	PostAttachmentContext(ctx context.Context, name string) (Attachment, error)
+	UpdateAttachment(id int, options *UpdateAttachmentOptions) (Attachment, error)
+	UpdateAttachmentContext(ctx context.Context, id int, options *UpdateAttachmentOptions) (Attachment, error)
+	DeleteAttachment(id int) error
+	DeleteAttachmentContext(ctx context.Context, id int) error
+}
@@ -21,6 +21,13 @@ func (t AttachmentType) String() string {
 	return string(t)
 }
 
+// The below constants define the possible statuses of attachments that
+// are uploads.
+const (
+	AttachmentPending  = "pending"
+	AttachmentUploaded = "uploaded"
+)
+
 // Attachment is a piece of media that belongs to a message.
 // It may represent a link, image, video, file upload or more.
 // Sqwiggle often adds new attachment types based on demand so it
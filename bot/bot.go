@@ -0,0 +1,216 @@
+// Package bot runs chat bots on Sqwiggle. A Bot watches the messages of
+// the organization, and routes every new message to the first handler
+// whose route matches it: a regular expression, a slash command, or a
+// mention of the bot user.
+//
+//	b := bot.New(client, botUserID)
+//	b.HandleCommand("/roll", func(ctx *bot.Context) error {
+//		_, err := ctx.Reply(fmt.Sprint(rand.Intn(6) + 1))
+//		return err
+//	})
+//	b.HandleMention(func(ctx *bot.Context) error {
+//		_, err := ctx.Reply("You rang?")
+//		return err
+//	}).Limit(1)
+//	err := b.Run(ctx)
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hermanschaaf/sqwiggle"
+	"github.com/hermanschaaf/sqwiggle/commands"
+)
+
+// Handler handles a message routed to it by a Bot.
+type Handler func(ctx *Context) error
+
+// Context is passed to a Handler with the message it handles. It is a
+// context.Context that is done when the bot stops.
+type Context struct {
+	context.Context
+	Message sqwiggle.Message // The message being handled
+	Command commands.Command // The command, for handlers registered with HandleCommand
+	Match   []string         // The submatches of the pattern, for handlers registered with HandleRegexp
+
+	bot *Bot
+}
+
+// Reply posts text to the stream of the message being handled.
+func (c *Context) Reply(text string) (sqwiggle.Message, error) {
+	return c.bot.API.PostMessageContext(c, c.Message.StreamID, text, nil)
+}
+
+// Edit replaces the text of the message with the given ID, such as an
+// earlier reply of the bot.
+func (c *Context) Edit(id int, text string) (sqwiggle.Message, error) {
+	return c.bot.API.UpdateMessageContext(c, id, text)
+}
+
+// Delete deletes the message with the given ID.
+func (c *Context) Delete(id int) error {
+	return c.bot.API.DeleteMessageContext(c, id)
+}
+
+// Route is a registered handler, along with the messages it handles.
+type Route struct {
+	match   func(ctx *Context) bool
+	handler Handler
+	sem     chan struct{}
+}
+
+// Limit makes at most n messages be handled by the route at the same
+// time; further messages wait for a handler to finish. It returns r so
+// that it can be chained to the registration of the route, and must not
+// be called once the bot is running.
+func (r *Route) Limit(n int) *Route {
+	if n > 0 {
+		r.sem = make(chan struct{}, n)
+	} else {
+		r.sem = nil
+	}
+	return r
+}
+
+// PanicError is reported for a handler that panicked.
+type PanicError struct {
+	Value interface{} // The value passed to panic
+	Stack []byte      // The stack trace of the panic
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("bot: handler panicked: %v", e.Value)
+}
+
+// Bot routes the messages posted in the organization to handlers.
+type Bot struct {
+	API      sqwiggle.MessagesAPI
+	UserID   int                                 // The ID of the bot user, whose own messages are ignored
+	Interval time.Duration                       // How often to poll for new messages, defaults to that of MessageWatcher
+	OnError  func(m sqwiggle.Message, err error) // Called, if set, with the errors of handlers and polls; may be called concurrently
+
+	routes []*Route
+}
+
+// New returns a Bot that uses api, and posts as the user with the given ID.
+func New(api sqwiggle.MessagesAPI, userID int) *Bot {
+	return &Bot{API: api, UserID: userID}
+}
+
+// handle registers a route.
+func (b *Bot) handle(match func(ctx *Context) bool, h Handler) *Route {
+	r := &Route{match: match, handler: h}
+	b.routes = append(b.routes, r)
+	return r
+}
+
+// HandleRegexp routes messages whose text matches pattern to h. The
+// submatches are passed in Context.Match.
+func (b *Bot) HandleRegexp(pattern *regexp.Regexp, h Handler) *Route {
+	return b.handle(func(ctx *Context) bool {
+		ctx.Match = pattern.FindStringSubmatch(ctx.Message.Text)
+		return ctx.Match != nil
+	}, h)
+}
+
+// HandleCommand routes the slash command with the given name, such as
+// "/deploy", to h. The parsed command is passed in Context.Command.
+func (b *Bot) HandleCommand(name string, h Handler) *Route {
+	name = strings.ToLower(name)
+	return b.handle(func(ctx *Context) bool {
+		cmd, ok := commands.ParseMessage(ctx.Message)
+		if !ok || cmd.Name != name {
+			return false
+		}
+		ctx.Command = cmd
+		return true
+	}, h)
+}
+
+// HandleMention routes messages that mention the bot user to h. Mentions
+// of clients or support agents that share the bot user's ID are ignored.
+func (b *Bot) HandleMention(h Handler) *Route {
+	return b.handle(func(ctx *Context) bool {
+		for _, mention := range ctx.Message.Mentions {
+			if mention.SubjectType == sqwiggle.TypeUser && mention.SubjectID == b.UserID {
+				return true
+			}
+		}
+		return false
+	}, h)
+}
+
+// Dispatch routes m to the first matching handler, and returns the error
+// of the handler. Messages posted by the bot user and deleted messages
+// are ignored. A handler that panics returns a *PanicError.
+func (b *Bot) Dispatch(ctx context.Context, m sqwiggle.Message) error {
+	if m.Author.ID == b.UserID || m.Removed() {
+		return nil
+	}
+	for _, r := range b.routes {
+		hctx := &Context{Context: ctx, Message: m, bot: b}
+		if r.match(hctx) {
+			return r.run(hctx)
+		}
+	}
+	return nil
+}
+
+// run runs the handler of the route, within its concurrency limit.
+func (r *Route) run(ctx *Context) (err error) {
+	if r.sem != nil {
+		select {
+		case r.sem <- struct{}{}:
+			defer func() { <-r.sem }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return r.handler(ctx)
+}
+
+// Run watches for new messages until ctx is done, and dispatches each of
+// them in its own goroutine. Messages posted before Run was called are
+// not handled. Run waits for running handlers to return, and returns
+// ctx.Err().
+func (b *Bot) Run(ctx context.Context) error {
+	w := sqwiggle.NewMessageWatcher(b.API, nil)
+	if b.Interval > 0 {
+		w.Interval = b.Interval
+	}
+	w.OnError = func(err error) { b.error(sqwiggle.Message{}, err) }
+
+	var wg sync.WaitGroup
+	for ev := range w.Watch(ctx) {
+		if ev.Type != sqwiggle.MessageCreated {
+			continue
+		}
+		wg.Add(1)
+		go func(m sqwiggle.Message) {
+			defer wg.Done()
+			if err := b.Dispatch(ctx, m); err != nil {
+				b.error(m, err)
+			}
+		}(ev.Message)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// error reports err to OnError, if it is set.
+func (b *Bot) error(m sqwiggle.Message, err error) {
+	if b.OnError != nil {
+		b.OnError(m, err)
+	}
+}
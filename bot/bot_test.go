@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hermanschaaf/sqwiggle"
+	"github.com/hermanschaaf/sqwiggle/sqwiggletest"
+)
+
+func TestBot_Dispatch(t *testing.T) {
+	server := sqwiggletest.NewServer("test")
+	defer server.Close()
+	self := server.Self()
+	other := server.AddUser(sqwiggle.User{Name: "Other"})
+	stream := server.AddStream(sqwiggle.Stream{Name: "General"})
+
+	b := New(server.Client(), self.ID)
+	var handled []string
+	b.HandleCommand("/Echo", func(ctx *Context) error {
+		handled = append(handled, "echo")
+		_, err := ctx.Reply(ctx.Command.Text)
+		return err
+	})
+	b.HandleMention(func(ctx *Context) error {
+		handled = append(handled, "mention")
+		return nil
+	})
+	b.HandleRegexp(regexp.MustCompile(`(?i)deploy (\w+)`), func(ctx *Context) error {
+		handled = append(handled, "deploy "+ctx.Match[1])
+		return nil
+	})
+	b.HandleRegexp(regexp.MustCompile(`panic`), func(ctx *Context) error {
+		panic("oops")
+	})
+
+	msg := func(text string, mentions ...int) sqwiggle.Message {
+		m := sqwiggle.Message{StreamID: stream.ID, Text: text, Author: sqwiggle.User{ID: other.ID}}
+		for _, id := range mentions {
+			m.Mentions = append(m.Mentions, sqwiggle.Mention{SubjectType: sqwiggle.TypeUser, SubjectID: id})
+		}
+		return m
+	}
+	ctx := context.Background()
+	for _, m := range []sqwiggle.Message{
+		msg("/echo hello there"),
+		msg("Test User, deploy prod", self.ID),
+		msg("please deploy staging"),
+		msg("Other, deploy nothing", other.ID),
+		{StreamID: stream.ID, Text: "Helpdesk, deploy qa", Author: sqwiggle.User{ID: other.ID},
+			Mentions: []sqwiggle.Mention{{SubjectType: sqwiggle.TypeClient, SubjectID: self.ID}}},
+		msg("nothing to see"),
+		{StreamID: stream.ID, Text: "/echo from myself", Author: sqwiggle.User{ID: self.ID}},
+		msg(sqwiggle.RemovedText),
+	} {
+		if err := b.Dispatch(ctx, m); err != nil {
+			t.Errorf("Dispatch(%q) returned error: %v", m.Text, err)
+		}
+	}
+	want := []string{"echo", "mention", "deploy staging", "deploy nothing", "deploy qa"}
+	if len(handled) != len(want) {
+		t.Fatalf("handled %v, want %v", handled, want)
+	}
+	for i := range want {
+		if handled[i] != want[i] {
+			t.Errorf("handled %v, want %v", handled, want)
+			break
+		}
+	}
+
+	msgs, _ := server.Client().ListStreamMessages(stream.ID, 0, 0)
+	if len(msgs) != 1 || msgs[0].Text != "hello there" || msgs[0].Author.ID != self.ID {
+		t.Errorf("stream messages = %+v, want the reply to /echo", msgs)
+	}
+
+	var pe *PanicError
+	if err := b.Dispatch(ctx, msg("panic!")); !errors.As(err, &pe) || pe.Value != "oops" {
+		t.Errorf("Dispatch of a panicking handler returned %v, want a PanicError", err)
+	}
+}
+
+func TestRoute_Limit(t *testing.T) {
+	b := New(nil, 1)
+	var mu sync.Mutex
+	running, max := 0, 0
+	b.HandleRegexp(regexp.MustCompile(`.`), func(ctx *Context) error {
+		mu.Lock()
+		running++
+		if running > max {
+			max = running
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}).Limit(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Dispatch(context.Background(), sqwiggle.Message{Text: "hi", Author: sqwiggle.User{ID: 2}})
+		}()
+	}
+	wg.Wait()
+	if max != 2 {
+		t.Errorf("at most %d handlers ran at the same time, want %d", max, 2)
+	}
+}
+
+func TestBot_Run(t *testing.T) {
+	server := sqwiggletest.NewServer("test")
+	defer server.Close()
+	other := server.AddUser(sqwiggle.User{Name: "Other"})
+	stream := server.AddStream(sqwiggle.Stream{Name: "General"})
+
+	// signal the first poll for messages, which records the messages
+	// that are already there
+	client := server.Client()
+	polled := make(chan struct{})
+	var once sync.Once
+	client.Middleware = append(client.Middleware, func(next sqwiggle.Handler) sqwiggle.Handler {
+		return func(op string, req *http.Request) (*http.Response, error) {
+			resp, err := next(op, req)
+			if op == "ListMessages" {
+				once.Do(func() { close(polled) })
+			}
+			return resp, err
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := New(client, server.Self().ID)
+	b.Interval = time.Millisecond
+	replied := make(chan sqwiggle.Message)
+	b.HandleCommand("/ping", func(ctx *Context) error {
+		m, err := ctx.Reply("pong")
+		replied <- m
+		return err
+	})
+	done := make(chan error)
+	go func() { done <- b.Run(ctx) }()
+
+	<-polled
+	server.AddMessage(sqwiggle.Message{StreamID: stream.ID, Text: "/ping", Author: sqwiggle.User{ID: other.ID}})
+	if m := <-replied; m.Text != "pong" || m.StreamID != stream.ID {
+		t.Errorf("reply = %+v, want pong in stream %d", m, stream.ID)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,283 @@
+// Package cassette provides an http.RoundTripper that records the
+// interactions of a Client with the Sqwiggle API to a file, and replays
+// them later, so that tests can run deterministically without network
+// access or an API key.
+//
+// Record a cassette once against the real API:
+//
+//	rec, err := cassette.New("testdata/messages.json", cassette.ModeRecord)
+//	client := sqwiggle.NewClient(os.Getenv("SQWIGGLE_API_KEY"))
+//	client.HTTPClient.Transport = rec
+//	// ... use client ...
+//	err = rec.Save()
+//
+// and replay it in tests with cassette.ModeReplay. The API key and email
+// addresses are scrubbed from everything that is recorded.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mode determines whether a Recorder records or replays interactions.
+type Mode int
+
+// These Mode constants define the modes a Recorder can run in.
+const (
+	ModeReplay Mode = iota // Replay recorded interactions, never touching the network
+	ModeRecord             // Make real requests and record them
+)
+
+// scrubbedKey and scrubbedEmail replace secrets in recorded interactions.
+const (
+	scrubbedKey   = "API-KEY"
+	scrubbedEmail = "user@example.com"
+)
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// Request is the recorded part of an HTTP request. Requests are matched
+// on all of these fields when replaying.
+type Request struct {
+	Method string     `json:"method"`
+	Path   string     `json:"path"`
+	Query  url.Values `json:"query,omitempty"`
+	Form   url.Values `json:"form,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Interaction is a single recorded request and the response to it.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Recorder is an http.RoundTripper that records or replays interactions,
+// depending on its Mode. It is safe for concurrent use.
+type Recorder struct {
+	// Transport is used to make real requests when recording. If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Scrub, if set, is applied to recorded request and response data
+	// in addition to the built-in scrubbing of API keys and emails.
+	Scrub func(string) string
+
+	path string
+	mode Mode
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New returns a Recorder for the cassette file at path. In ModeReplay the
+// cassette is loaded from the file, which must exist; in ModeRecord the
+// cassette starts out empty, and is written to the file by Save.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode}
+	if mode == ModeReplay {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &r.interactions); err != nil {
+			return nil, fmt.Errorf("cassette: reading %s: %v", path, err)
+		}
+		r.used = make([]bool, len(r.interactions))
+	}
+	return r, nil
+}
+
+// Save writes the recorded interactions to the cassette file. It does
+// nothing in ModeReplay.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, append(b, '\n'), 0644)
+}
+
+// Unused returns the recorded requests that have not been replayed yet,
+// which is useful to check that a test made all the expected requests.
+func (r *Recorder) Unused() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var reqs []Request
+	for i, used := range r.used {
+		if !used {
+			reqs = append(reqs, r.interactions[i].Request)
+		}
+	}
+	return reqs
+}
+
+// RoundTrip is an implementation of the http.RoundTripper interface
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := r.request(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+	return r.record(req, recorded)
+}
+
+// request builds the scrubbed record of req, leaving req readable.
+func (r *Recorder) request(req *http.Request) (Request, error) {
+	scrub := r.scrubber(req)
+	recorded := Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  scrubValues(req.URL.Query(), scrub),
+	}
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return recorded, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		form, err := url.ParseQuery(string(b))
+		if err != nil {
+			return recorded, fmt.Errorf("cassette: parsing form of %s %s: %v", req.Method, req.URL.Path, err)
+		}
+		recorded.Form = scrubValues(form, scrub)
+	}
+	return recorded, nil
+}
+
+// replay returns the first unused recorded response to a request matching
+// recorded, or an error if there is none.
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.interactions {
+		if r.used[i] || !matches(in.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          ioutil.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette: no recorded interaction in %s matches %s %s (query %v, form %v)",
+		r.path, recorded.Method, recorded.Path, recorded.Query, recorded.Form)
+}
+
+// record makes the real request and records the interaction.
+func (r *Recorder) record(req *http.Request, recorded Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	scrub := r.scrubber(req)
+	header := http.Header{}
+	for _, k := range []string{"Content-Type", "Retry-After", "X-Ratelimit-Limit", "X-Ratelimit-Remaining", "X-Ratelimit-Reset"} {
+		if v := resp.Header.Get(k); v != "" {
+			header.Set(k, v)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       scrub(string(b)),
+		},
+	})
+	r.used = append(r.used, true)
+	return resp, nil
+}
+
+// scrubber returns a function that removes the API key used by req, any
+// email addresses, and whatever r.Scrub removes from a string.
+func (r *Recorder) scrubber(req *http.Request) func(string) string {
+	key, _, _ := req.BasicAuth()
+	return func(s string) string {
+		if key != "" {
+			s = strings.ReplaceAll(s, key, scrubbedKey)
+		}
+		s = emailPattern.ReplaceAllString(s, scrubbedEmail)
+		if r.Scrub != nil {
+			s = r.Scrub(s)
+		}
+		return s
+	}
+}
+
+// scrubValues applies scrub to all values in v, returning nil for empty v
+// so that recorded and replayed requests compare equal.
+func scrubValues(v url.Values, scrub func(string) string) url.Values {
+	if len(v) == 0 {
+		return nil
+	}
+	out := make(url.Values, len(v))
+	for k, vals := range v {
+		for _, val := range vals {
+			out[k] = append(out[k], scrub(val))
+		}
+	}
+	return out
+}
+
+// matches reports whether a recorded request matches a new one.
+func matches(a, b Request) bool {
+	return a.Method == b.Method && a.Path == b.Path &&
+		reflect.DeepEqual(a.Query, b.Query) && reflect.DeepEqual(a.Form, b.Form)
+}
+
+// ModeFor returns ModeReplay if the cassette file at path exists, and
+// ModeRecord otherwise, so that cassettes are recorded on the first run
+// and replayed from then on.
+func ModeFor(path string) Mode {
+	if _, err := os.Stat(path); err == nil {
+		return ModeReplay
+	}
+	return ModeRecord
+}
@@ -0,0 +1,98 @@
+package cassette
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hermanschaaf/sqwiggle"
+	"github.com/hermanschaaf/sqwiggle/sqwiggletest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// record against a fake server
+	server := sqwiggletest.NewServer("secret-key")
+	stream := server.AddStream(sqwiggle.Stream{Name: "General"})
+
+	rec, err := New(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := server.Client()
+	client.HTTPClient.Transport = rec
+
+	if _, err := client.PostMessage(stream.ID, "Mail me at someone@example.org", nil); err != nil {
+		t.Fatal("got error:", err)
+	}
+	if _, err := client.PostInvite("friend@example.org"); err != nil {
+		t.Fatal("got error:", err)
+	}
+	wantMsgs, err := client.ListMessages(1, 10)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-key", "someone@example.org", "friend@example.org"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+
+	// replay without the server
+	rec, err = New(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = sqwiggle.NewClient("another-key")
+	client.RootURL = "http://sqwiggle.invalid"
+	client.HTTPClient.Transport = rec
+
+	if _, err := client.PostMessage(stream.ID, "Mail me at someone@example.org", nil); err != nil {
+		t.Fatal("got error:", err)
+	}
+	if _, err := client.PostInvite("friend@example.org"); err != nil {
+		t.Fatal("got error:", err)
+	}
+	msgs, err := client.ListMessages(1, 10)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if len(msgs) != len(wantMsgs) || msgs[0].ID != wantMsgs[0].ID {
+		t.Errorf("replayed %+v, want %+v", msgs, wantMsgs)
+	}
+	if unused := rec.Unused(); len(unused) != 0 {
+		t.Errorf("Unused() = %+v, want none", unused)
+	}
+
+	// requests that were not recorded fail loudly
+	_, err = client.ListMessages(2, 10)
+	if err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("err = %v, want unmatched request error", err)
+	}
+	// and recorded requests are only replayed once
+	_, err = client.PostInvite("friend@example.org")
+	if err == nil {
+		t.Error("replaying an interaction twice succeeded, want error")
+	}
+}
+
+func TestNew_MissingCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := New(path, ModeReplay); err == nil {
+		t.Error("New with missing cassette in replay mode succeeded, want error")
+	}
+	if mode := ModeFor(path); mode != ModeRecord {
+		t.Errorf("ModeFor(missing) = %v, want ModeRecord", mode)
+	}
+}
@@ -0,0 +1,216 @@
+// Package commands parses the slash commands that Sqwiggle users type in
+// chat, such as "/busy In a meeting" or "/ping @(Jane)[user:7]", and
+// implements the commands that change a user's status on the client side.
+//
+// The commands known to the server, with their descriptions, are listed
+// by the /info endpoint and can be loaded into a Catalog:
+//
+//	catalog, err := commands.LoadCatalog(ctx, client)
+//	if cmd, ok := catalog.Parse(text); ok {
+//		user, err := commands.NewRunner(client, userID).Run(ctx, cmd)
+//	}
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hermanschaaf/sqwiggle"
+)
+
+// Catalog maps the names of slash commands, such as "/busy", to their
+// descriptions.
+type Catalog map[string]string
+
+// NewCatalog returns the catalog of commands listed in info.
+func NewCatalog(info sqwiggle.Info) Catalog {
+	catalog := make(Catalog, len(info.Configuration.Commands))
+	for name, description := range info.Configuration.Commands {
+		catalog[strings.ToLower(name)] = description
+	}
+	return catalog
+}
+
+// LoadCatalog fetches the catalog of commands from /info.
+func LoadCatalog(ctx context.Context, api sqwiggle.InfoAPI) (Catalog, error) {
+	info, err := api.GetInfoTypedContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewCatalog(info), nil
+}
+
+// Names returns the names of the commands in the catalog, sorted.
+func (c Catalog) Names() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse is like the package-level Parse, but only accepts the commands
+// in the catalog.
+func (c Catalog) Parse(text string) (Command, bool) {
+	cmd, ok := Parse(text)
+	if !ok {
+		return Command{}, false
+	}
+	if _, known := c[cmd.Name]; !known {
+		return Command{}, false
+	}
+	return cmd, true
+}
+
+// Command is a slash command typed in chat.
+type Command struct {
+	Name     string             // The name of the command, in lower case and including the slash, e.g. "/busy"
+	Args     []string           // The whitespace-separated arguments, with mentions rendered as the names of the mentioned users
+	Mentions []sqwiggle.Mention // The users mentioned in the arguments
+	Text     string             // Everything after the command name, with mentions rendered as names, e.g. the status message of "/busy"
+}
+
+// commandPattern matches the name of a command at the start of a message.
+var commandPattern = regexp.MustCompile(`^\s*(/[A-Za-z][A-Za-z0-9_-]*)(?:\s+|$)`)
+
+// Parse parses a slash command from the text of a message as it is
+// posted, in which mentions use the @(user_name)[user:user_id] syntax.
+// It returns false if the text is not a command. The indices of the
+// returned mentions are rune offsets into Text.
+func Parse(text string) (Command, bool) {
+	m := commandPattern.FindStringSubmatchIndex(text)
+	if m == nil {
+		return Command{}, false
+	}
+	rest := strings.TrimRightFunc(text[m[1]:], unicode.IsSpace)
+	return newCommand(text[m[2]:m[3]], sqwiggle.ParseMentions(rest)), true
+}
+
+// ParseMessage is like Parse, but parses a message as it is returned by
+// the API, in which mentions have been replaced by the names of the
+// mentioned users and are described by the Mentions of the message.
+func ParseMessage(msg sqwiggle.Message) (Command, bool) {
+	segs := msg.Segments()
+	if len(segs) == 0 || segs[0].Mention != nil {
+		return Command{}, false
+	}
+	m := commandPattern.FindStringSubmatchIndex(segs[0].Text)
+	if m == nil {
+		return Command{}, false
+	}
+	name := segs[0].Text[m[2]:m[3]]
+	segs[0].Text = segs[0].Text[m[1]:]
+	if last := &segs[len(segs)-1]; last.Mention == nil {
+		last.Text = strings.TrimRightFunc(last.Text, unicode.IsSpace)
+	}
+	return newCommand(name, segs), true
+}
+
+// newCommand returns the command with the given name, whose arguments
+// are made up of segs.
+func newCommand(name string, segs []sqwiggle.Segment) Command {
+	cmd := Command{Name: strings.ToLower(name)}
+	var spans [][2]int // byte offsets of the mentions in cmd.Text, kept in one argument
+	for _, s := range segs {
+		if s.Mention != nil {
+			spans = append(spans, [2]int{len(cmd.Text), len(cmd.Text) + len(s.Text)})
+		}
+		cmd.Text += s.Text
+	}
+	if _, mentions := sqwiggle.RenderSegments(segs); len(mentions) > 0 {
+		cmd.Mentions = mentions
+	}
+	cmd.Args = split(cmd.Text, spans)
+	return cmd
+}
+
+// split splits text around whitespace, except within the given spans.
+func split(text string, spans [][2]int) []string {
+	var args []string
+	start := -1
+	for i, r := range text {
+		for len(spans) > 0 && i >= spans[0][1] {
+			spans = spans[1:]
+		}
+		inSpan := len(spans) > 0 && i >= spans[0][0]
+		switch {
+		case unicode.IsSpace(r) && !inSpan:
+			if start >= 0 {
+				args = append(args, text[start:i])
+				start = -1
+			}
+		case start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		args = append(args, text[start:])
+	}
+	return args
+}
+
+// ErrUnsupported is returned by Runner.Run for commands that are only
+// implemented by the Sqwiggle apps, such as "/mute".
+var ErrUnsupported = errors.New("commands: command is not supported by the client")
+
+// statusCommands maps the names of the commands that change the status of
+// a user to the updates they make. Empty names are rejected by the
+// validation of UpdateUserOptions.
+var statusCommands = map[string]func(cmd Command) *sqwiggle.UpdateUserOptions{
+	"/busy": func(cmd Command) *sqwiggle.UpdateUserOptions {
+		return &sqwiggle.UpdateUserOptions{Status: sqwiggle.Status(sqwiggle.StatusBusy), Message: sqwiggle.String(cmd.Text)}
+	},
+	"/brb": func(cmd Command) *sqwiggle.UpdateUserOptions {
+		return &sqwiggle.UpdateUserOptions{Status: sqwiggle.Status(sqwiggle.StatusBusy), Message: sqwiggle.String("Be Right Back")}
+	},
+	"/dnd": func(cmd Command) *sqwiggle.UpdateUserOptions {
+		return &sqwiggle.UpdateUserOptions{Status: sqwiggle.Status(sqwiggle.StatusBusy), Message: sqwiggle.String("Do Not Disturb")}
+	},
+	"/available": func(cmd Command) *sqwiggle.UpdateUserOptions {
+		return &sqwiggle.UpdateUserOptions{Status: sqwiggle.Status(sqwiggle.StatusAvailable), Message: sqwiggle.String(cmd.Text)}
+	},
+	"/unbusy": func(cmd Command) *sqwiggle.UpdateUserOptions {
+		return &sqwiggle.UpdateUserOptions{Status: sqwiggle.Status(sqwiggle.StatusAvailable), Message: sqwiggle.String("")}
+	},
+	"/status": func(cmd Command) *sqwiggle.UpdateUserOptions {
+		return &sqwiggle.UpdateUserOptions{Message: sqwiggle.String(cmd.Text)}
+	},
+	"/nick": func(cmd Command) *sqwiggle.UpdateUserOptions {
+		return &sqwiggle.UpdateUserOptions{Name: sqwiggle.String(cmd.Text)}
+	},
+}
+
+// Supported reports whether Runner.Run implements the named command.
+func Supported(name string) bool {
+	_, ok := statusCommands[strings.ToLower(name)]
+	return ok
+}
+
+// Runner runs the commands that change the status or name of a user on
+// the client side, by updating the user with the API.
+type Runner struct {
+	API    sqwiggle.UsersAPI
+	UserID int // The user whose status the commands change
+}
+
+// NewRunner returns a Runner that updates the user with the given ID.
+func NewRunner(api sqwiggle.UsersAPI, userID int) *Runner {
+	return &Runner{API: api, UserID: userID}
+}
+
+// Run runs cmd, and returns the updated user. It returns ErrUnsupported
+// for commands that are not in the list of supported commands: /busy,
+// /brb, /dnd, /available, /unbusy, /status and /nick.
+func (r *Runner) Run(ctx context.Context, cmd Command) (sqwiggle.User, error) {
+	update, ok := statusCommands[cmd.Name]
+	if !ok {
+		return sqwiggle.User{}, fmt.Errorf("%w: %s", ErrUnsupported, cmd.Name)
+	}
+	return r.API.UpdateUserContext(ctx, r.UserID, update(cmd))
+}
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hermanschaaf/sqwiggle"
+	"github.com/hermanschaaf/sqwiggle/sqwiggletest"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want Command
+	}{
+		{"/brb", Command{Name: "/brb"}},
+		{"  /BUSY  In a meeting ", Command{Name: "/busy", Args: []string{"In", "a", "meeting"}, Text: "In a meeting"}},
+		{"/ping @(Test User)[user:7] now", Command{
+			Name: "/ping",
+			Args: []string{"Test User", "now"},
+			Text: "Test User now",
+			Mentions: []sqwiggle.Mention{
+				{Name: "Test User", Text: "Test User", Indices: []int{0, 9}, SubjectType: sqwiggle.TypeUser, SubjectID: 7},
+			},
+		}},
+		{"/me waves at @(Zoë)[user:2]", Command{
+			Name: "/me",
+			Args: []string{"waves", "at", "Zoë"},
+			Text: "waves at Zoë",
+			Mentions: []sqwiggle.Mention{
+				{Name: "Zoë", Text: "Zoë", Indices: []int{9, 12}, SubjectType: sqwiggle.TypeUser, SubjectID: 2},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.text)
+		if !ok {
+			t.Errorf("Parse(%q) did not parse a command", tt.text)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+
+	for _, text := range []string{"", "hello /busy", "/", "/ busy", "//busy", "/1up"} {
+		if cmd, ok := Parse(text); ok {
+			t.Errorf("Parse(%q) = %+v, want no command", text, cmd)
+		}
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	// "/ping @(Jane (JD) Doe)[user:7] and @(Zoë)[user:2] " as returned by the API
+	msg := sqwiggle.Message{
+		Text: "/ping Jane (JD) Doe and Zoë ",
+		Mentions: []sqwiggle.Mention{
+			{ID: 2, Name: "Zoë", Indices: []int{24, 27}, SubjectType: sqwiggle.TypeUser, SubjectID: 2},
+			{ID: 1, Name: "Jane (JD) Doe", Indices: []int{6, 19}, SubjectType: sqwiggle.TypeUser, SubjectID: 7},
+		},
+	}
+	want, _ := Parse(`/ping @(Jane \(JD\) Doe)[user:7] and @(Zoë)[user:2] `)
+	got, ok := ParseMessage(msg)
+	if !ok {
+		t.Fatal("ParseMessage did not parse a command")
+	}
+	// the mentions of the message keep their IDs
+	for i := range got.Mentions {
+		got.Mentions[i].ID = 0
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMessage = %+v, want %+v", got, want)
+	}
+	if len(got.Args) != 3 || got.Args[0] != "Jane (JD) Doe" {
+		t.Errorf("Args = %q, want the mention of Jane as the first argument", got.Args)
+	}
+
+	msg = sqwiggle.Message{Text: "Test User /busy", Mentions: []sqwiggle.Mention{{Indices: []int{0, 9}}}}
+	if cmd, ok := ParseMessage(msg); ok {
+		t.Errorf("ParseMessage(%q) = %+v, want no command", msg.Text, cmd)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	server := sqwiggletest.NewServer("test")
+	defer server.Close()
+
+	catalog, err := LoadCatalog(context.Background(), server.Client())
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if catalog["/dnd"] != `Changes to busy with a message of "Do Not Disturb"` {
+		t.Errorf("catalog[/dnd] = %q, want the description from /info", catalog["/dnd"])
+	}
+	if names := catalog.Names(); len(names) != 14 || names[0] != "/available" {
+		t.Errorf("Names() = %v, want 14 commands starting with /available", names)
+	}
+	if _, ok := catalog.Parse("/gif cats"); !ok {
+		t.Error("Parse(/gif cats) did not parse a known command")
+	}
+	if _, ok := catalog.Parse("/nope"); ok {
+		t.Error("Parse(/nope) parsed an unknown command")
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	server := sqwiggletest.NewServer("test")
+	defer server.Close()
+	runner := NewRunner(server.Client(), server.Self().ID)
+
+	tests := []struct {
+		text    string
+		status  sqwiggle.UserStatus
+		message string
+	}{
+		{"/dnd", sqwiggle.StatusBusy, "Do Not Disturb"},
+		{"/unbusy", sqwiggle.StatusAvailable, ""},
+		{"/busy Lunch with @(Test User)[user:1]", sqwiggle.StatusBusy, "Lunch with Test User"},
+		{"/status Back soon", sqwiggle.StatusBusy, "Back soon"},
+		{"/available Here", sqwiggle.StatusAvailable, "Here"},
+		{"/brb", sqwiggle.StatusBusy, "Be Right Back"},
+	}
+	for _, tt := range tests {
+		cmd, _ := Parse(tt.text)
+		u, err := runner.Run(context.Background(), cmd)
+		if err != nil {
+			t.Fatalf("Run(%q) returned error: %v", tt.text, err)
+		}
+		if u.Status != tt.status || u.Message != tt.message {
+			t.Errorf("Run(%q) set status %q and message %q, want %q and %q", tt.text, u.Status, u.Message, tt.status, tt.message)
+		}
+	}
+
+	cmd, _ := Parse("/nick Jane Doe")
+	if u, err := runner.Run(context.Background(), cmd); err != nil || u.Name != "Jane Doe" {
+		t.Errorf("Run(/nick Jane Doe) = %q, %v, want name %q", u.Name, err, "Jane Doe")
+	}
+
+	cmd, _ = Parse("/nick")
+	if _, err := runner.Run(context.Background(), cmd); !errors.Is(err, sqwiggle.ErrInvalidParam) {
+		t.Errorf("Run(/nick) returned %v, want %q", err, sqwiggle.ErrInvalidParam)
+	}
+
+	cmd, _ = Parse("/mute")
+	if _, err := runner.Run(context.Background(), cmd); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Run(/mute) returned %v, want %v", err, ErrUnsupported)
+	}
+	if Supported("/mute") || !Supported("/DND") {
+		t.Error("Supported does not match the commands implemented by Run")
+	}
+}
@@ -1,5 +1,11 @@
 package sqwiggle
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
 // Error represents an error that could be returned by the Sqwiggle API
 type Error struct {
 	Type    ErrorType `json:"type"`
@@ -13,23 +19,102 @@ func (err Error) Error() string {
 	return err.Message
 }
 
+// Is reports whether target is the ErrorType of this error, so that
+// errors.Is(err, ErrValidation) can be used to check the type of an error.
+func (err Error) Is(target error) bool {
+	t, ok := target.(ErrorType)
+	return ok && t == err.Type
+}
+
 // ErrorType is a type that describes the type of error returned
 // by the Sqwiggle API. The constants below are defined to use this
 // type.
+//
+// ErrorType implements the error interface, so that the constants can
+// be used as sentinel values with errors.Is.
 type ErrorType string
 
 func (e ErrorType) String() string {
 	return string(e)
 }
 
+// Error is an implementation of the error interface
+func (e ErrorType) Error() string {
+	return string(e)
+}
+
 // These errors define the types of errors expected to be returned
 // by the Sqwiggle API.
 const (
 	ErrAuthentication ErrorType = "authentication"
-	ErrAuthorization            = "authorization"
-	ErrInvalidParam             = "invalid_param"
-	ErrUnknownParam             = "unknown_param"
-	ErrLimitReached             = "limit_reached"
-	ErrValidation               = "validation"
-	ErrUnknown                  = "unknown"
+	ErrAuthorization  ErrorType = "authorization"
+	ErrInvalidParam   ErrorType = "invalid_param"
+	ErrUnknownParam   ErrorType = "unknown_param"
+	ErrLimitReached   ErrorType = "limit_reached"
+	ErrValidation     ErrorType = "validation"
+	ErrUnknown        ErrorType = "unknown"
 )
+
+// maxErrorBody is the maximum number of bytes of a response body kept
+// in an APIError.
+const maxErrorBody = 4096
+
+// APIError is returned by Client methods when the API responds with an
+// unexpected status code. It holds the Error decoded from the response,
+// along with the details of the HTTP exchange. If the response body could
+// not be decoded, for example because a proxy returned an HTML error page,
+// Err has type ErrUnknown and Body holds the raw response.
+//
+// APIError unwraps to its Error, and matches the ErrorType constants with
+// errors.Is:
+//
+//	if errors.Is(err, sqwiggle.ErrAuthentication) {
+//		// ask for a new API key
+//	}
+type APIError struct {
+	Err        Error       // The error returned by the API
+	StatusCode int         // HTTP status code of the response
+	Method     string      // HTTP method of the request
+	Path       string      // URL path of the request
+	Header     http.Header // Headers of the response
+	Body       []byte      // Raw response body, truncated to 4KB
+}
+
+// newAPIError builds an APIError from an unexpected response.
+func newAPIError(method, path string, statusCode int, header http.Header, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: statusCode,
+		Method:     method,
+		Path:       path,
+		Header:     header,
+		Body:       body,
+	}
+	if len(e.Body) > maxErrorBody {
+		e.Body = e.Body[:maxErrorBody]
+	}
+	if err := json.Unmarshal(body, &e.Err); err != nil || e.Err.Type == "" {
+		e.Err = Error{
+			Type:    ErrUnknown,
+			Message: http.StatusText(statusCode),
+		}
+	}
+	return e
+}
+
+// hasErrorBody reports whether body holds an error returned by the API,
+// as opposed to, say, the plain text page of a proxy or web server.
+func hasErrorBody(body []byte) bool {
+	var e Error
+	return json.Unmarshal(body, &e) == nil && e.Type != ""
+}
+
+// Error is an implementation of the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("sqwiggle: %s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, e.Err.Type, e.Err.Message)
+}
+
+// Unwrap returns the Error returned by the API, so that errors.As can be
+// used to retrieve it.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
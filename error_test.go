@@ -0,0 +1,70 @@
+package sqwiggle
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Test_APIError_NonJSON checks that an HTML error page from a proxy
+// results in an APIError that keeps the status code and raw body.
+func Test_APIError_NonJSON(t *testing.T) {
+	page := "<html><body><h1>502 Bad Gateway</h1></body></html>"
+	server, client := setupTestServer(502, []byte(page), func(r *http.Request) {})
+	defer server.Close()
+
+	_, err := client.GetStream(1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, http.StatusBadGateway)
+	}
+	if apiErr.Method != "GET" || apiErr.Path != "/streams/1" {
+		t.Errorf("request = %s %s, want GET /streams/1", apiErr.Method, apiErr.Path)
+	}
+	if string(apiErr.Body) != page {
+		t.Errorf("Body = %q, want %q", apiErr.Body, page)
+	}
+	if !errors.Is(err, ErrUnknown) {
+		t.Errorf("errors.Is(%v, ErrUnknown) = false, want true", err)
+	}
+}
+
+// Test_APIError_Is checks that APIErrors match their ErrorType, and only
+// their ErrorType, with errors.Is.
+func Test_APIError_Is(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/error.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, client := setupTestServer(401, dummy, func(r *http.Request) {})
+	defer server.Close()
+
+	err = client.DeleteInvite(1)
+	if !errors.Is(err, ErrAuthentication) {
+		t.Errorf("errors.Is(%v, ErrAuthentication) = false, want true", err)
+	}
+	if errors.Is(err, ErrValidation) {
+		t.Errorf("errors.Is(%v, ErrValidation) = true, want false", err)
+	}
+
+	var sqErr Error
+	if !errors.As(err, &sqErr) || sqErr.Type != ErrAuthentication {
+		t.Errorf("errors.As(%v, &Error) = %+v, want Error of type %q", err, sqErr, ErrAuthentication)
+	}
+}
+
+func TestNewAPIError_Truncate(t *testing.T) {
+	body := []byte(strings.Repeat("x", 2*maxErrorBody))
+	e := newAPIError("GET", "/info", 500, nil, body)
+	if len(e.Body) != maxErrorBody {
+		t.Errorf("len(Body) = %d, want %d", len(e.Body), maxErrorBody)
+	}
+	if e.StatusCode != 500 {
+		t.Errorf("StatusCode = %d, want %d", e.StatusCode, 500)
+	}
+}
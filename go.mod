@@ -0,0 +1,3 @@
+module github.com/hermanschaaf/sqwiggle
+
+go 1.23
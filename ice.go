@@ -0,0 +1,140 @@
+package sqwiggle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultICERefreshBefore is how long before the ICE server credentials
+// expire an ICEServers refreshes them by default.
+const defaultICERefreshBefore = 5 * time.Minute
+
+// minICECacheTTL is how long an ICEServers caches servers whose expiry
+// /info does not report.
+const minICECacheTTL = 5 * time.Minute
+
+// RTCIceServer is an ICE server in the format of the WebRTC RTCIceServer
+// dictionary, so that it can be marshalled to JSON and handed to a WebRTC
+// implementation as is.
+type RTCIceServer struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// RTCIceServers converts the ICE servers of the configuration to the
+// WebRTC RTCIceServer format.
+func (c InfoConfiguration) RTCIceServers() []RTCIceServer {
+	servers := make([]RTCIceServer, len(c.ICEServers))
+	for i, s := range c.ICEServers {
+		servers[i] = RTCIceServer{
+			URLs:       []string{s.URL},
+			Username:   s.Username,
+			Credential: s.Credential,
+		}
+	}
+	return servers
+}
+
+// ICEServers keeps the ICE servers advertised by /info up to date. The
+// TURN credentials returned by /info expire, so the servers are fetched
+// again when they are requested less than RefreshBefore before the
+// credentials expire, or continuously in the background with Run. If
+// /info does not say when the credentials expire, the servers are cached
+// for 5 minutes. An ICEServers is safe for concurrent use.
+type ICEServers struct {
+	Client        *Client
+	RefreshBefore time.Duration // How long before expiry to refresh, defaults to 5 minutes
+
+	mu        sync.Mutex
+	servers   []RTCIceServer
+	expiresAt time.Time
+	fetchedAt time.Time
+}
+
+// NewICEServers returns an ICEServers that fetches from c.
+func NewICEServers(c *Client) *ICEServers {
+	return &ICEServers{Client: c, RefreshBefore: defaultICERefreshBefore}
+}
+
+// Servers returns a copy of the current ICE servers and the time their
+// credentials expire, fetching them from /info if they have not been
+// fetched yet or are about to expire.
+func (s *ICEServers) Servers(ctx context.Context) ([]RTCIceServer, time.Time, error) {
+	s.mu.Lock()
+	fresh := s.servers != nil && time.Now().Before(s.refreshAt())
+	servers, expiresAt := copyICEServers(s.servers), s.expiresAt
+	s.mu.Unlock()
+	if fresh {
+		return servers, expiresAt, nil
+	}
+	return s.refresh(ctx)
+}
+
+// Run refreshes the ICE servers shortly before their credentials expire
+// until ctx is done, and returns ctx.Err(). If a refresh fails, or returns
+// credentials that are already due for a refresh, it is retried after a
+// minute. Run calls updated, if it is not nil, with a copy of the
+// servers after every successful refresh.
+func (s *ICEServers) Run(ctx context.Context, updated func([]RTCIceServer, time.Time)) error {
+	for {
+		servers, expiresAt, err := s.refresh(ctx)
+		s.mu.Lock()
+		wait := time.Until(s.refreshAt())
+		s.mu.Unlock()
+
+		if err == nil && updated != nil {
+			updated(servers, expiresAt)
+		}
+		if err != nil || wait <= 0 {
+			wait = time.Minute
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// refreshAt returns the time the servers should be refreshed. s.mu must
+// be held.
+func (s *ICEServers) refreshAt() time.Time {
+	if s.expiresAt.IsZero() {
+		return s.fetchedAt.Add(minICECacheTTL)
+	}
+	before := s.RefreshBefore
+	if before <= 0 {
+		before = defaultICERefreshBefore
+	}
+	return s.expiresAt.Add(-before)
+}
+
+// refresh fetches the servers from /info, stores them and returns a copy.
+// The fetch is made without holding s.mu.
+func (s *ICEServers) refresh(ctx context.Context) ([]RTCIceServer, time.Time, error) {
+	info, err := s.Client.GetInfoTypedContext(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	servers := info.Configuration.RTCIceServers()
+	expiresAt := info.Configuration.ICEServersExpireAt
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.servers, s.expiresAt, s.fetchedAt = servers, expiresAt, time.Now()
+	return copyICEServers(servers), expiresAt, nil
+}
+
+// copyICEServers returns a deep copy of servers, so that callers cannot
+// modify the cached servers.
+func copyICEServers(servers []RTCIceServer) []RTCIceServer {
+	if servers == nil {
+		return nil
+	}
+	c := make([]RTCIceServer, len(servers))
+	for i, s := range servers {
+		c[i] = s
+		c[i].URLs = append([]string(nil), s.URLs...)
+	}
+	return c
+}
@@ -0,0 +1,125 @@
+package sqwiggle
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setupICEServer returns a server whose /info returns a TURN server with a
+// new username on every request, with credentials that expire after ttl.
+func setupICEServer(ttl time.Duration) (*httptest.Server, *Client, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		fmt.Fprintf(w, `{"configuration": {"iceservers": [{"url": "stun:stun.example.com"}, {"url": "turn:turn.example.com", "username": "user%d", "credential": "secret"}], "iceservers_expire_at": %q}}`,
+			n, time.Now().Add(ttl).Format(time.RFC3339Nano))
+	}))
+	client := NewClient("test")
+	client.RootURL = server.URL
+	return server, client, &calls
+}
+
+func TestICEServers_Servers(t *testing.T) {
+	server, client, calls := setupICEServer(time.Hour)
+	defer server.Close()
+
+	ice := NewICEServers(client)
+	servers, expiresAt, err := ice.Servers(context.Background())
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	want := []RTCIceServer{
+		{URLs: []string{"stun:stun.example.com"}},
+		{URLs: []string{"turn:turn.example.com"}, Username: "user1", Credential: "secret"},
+	}
+	if !reflect.DeepEqual(servers, want) {
+		t.Errorf("Servers = %+v, want %+v", servers, want)
+	}
+	if d := time.Until(expiresAt); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expiresAt = %v, want about an hour from now", expiresAt)
+	}
+
+	// the credentials are cached until shortly before they expire
+	ice.Servers(context.Background())
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("GET /info called %d times, want %d", n, 1)
+	}
+	ice.RefreshBefore = 2 * time.Hour
+	servers, _, _ = ice.Servers(context.Background())
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("GET /info called %d times, want %d", n, 2)
+	}
+	if servers[1].Username != "user2" {
+		t.Errorf("Username = %q, want %q", servers[1].Username, "user2")
+	}
+}
+
+func TestICEServers_Run(t *testing.T) {
+	server, client, _ := setupICEServer(time.Minute + 20*time.Millisecond)
+	defer server.Close()
+
+	ice := NewICEServers(client)
+	ice.RefreshBefore = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var usernames []string
+	err := ice.Run(ctx, func(servers []RTCIceServer, expiresAt time.Time) {
+		usernames = append(usernames, servers[1].Username)
+		if len(usernames) == 3 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Errorf("Run returned %v, want %v", err, context.Canceled)
+	}
+	if want := []string{"user1", "user2", "user3"}; !reflect.DeepEqual(usernames, want) {
+		t.Errorf("usernames = %v, want %v", usernames, want)
+	}
+}
+
+func TestICEServers_Copy(t *testing.T) {
+	server, client, _ := setupICEServer(time.Hour)
+	defer server.Close()
+
+	ice := NewICEServers(client)
+	servers, _, err := ice.Servers(context.Background())
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	servers[0].URLs[0] = "stun:changed.example.com"
+	servers[1].Username = "changed"
+
+	servers, _, _ = ice.Servers(context.Background())
+	if servers[0].URLs[0] != "stun:stun.example.com" || servers[1].Username != "user1" {
+		t.Errorf("Servers = %+v after modifying the returned servers, want the cached servers unchanged", servers)
+	}
+}
+
+func TestICEServers_NoExpiry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"configuration": {"iceservers": [{"url": "stun:stun.example.com"}]}}`))
+	}))
+	defer server.Close()
+	client := NewClient("test")
+	client.RootURL = server.URL
+
+	// servers without an expiry are cached, not fetched on every call
+	ice := NewICEServers(client)
+	for i := 0; i < 3; i++ {
+		servers, expiresAt, err := ice.Servers(context.Background())
+		if err != nil || len(servers) != 1 || !expiresAt.IsZero() {
+			t.Fatalf("Servers = %+v, %v, %v, want one server without an expiry", servers, expiresAt, err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("GET /info called %d times, want %d", n, 1)
+	}
+}
@@ -0,0 +1,90 @@
+package sqwiggle
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Info is the response of GET /info, which describes the configuration
+// of the Sqwiggle service and the current releases of its apps. The
+// response is not documented, so any keys that are not known to Info are
+// kept in Extra, and can be decoded by hand.
+type Info struct {
+	Configuration InfoConfiguration  `json:"configuration"`
+	Releases      map[string]Release `json:"releases"` // Keyed by platform: windows, ios or mac
+
+	Extra map[string]json.RawMessage `json:"-"` // Unknown top-level keys
+}
+
+// InfoConfiguration describes the configuration of the Sqwiggle service.
+type InfoConfiguration struct {
+	Storage                     InfoStorage       `json:"storage"`
+	Commands                    map[string]string `json:"commands"`                      // Slash commands, such as "/busy", mapped to their description
+	MaxUploadFilesize           int64             `json:"max_upload_filesize"`           // Maximum size of an upload in bytes
+	MaxConversationParticipants int               `json:"max_conversation_participants"` // Maximum number of users in a conversation
+	MaxFreeStreams              int               `json:"max_free_streams"`              // Maximum number of streams an organization may create for free
+	ICEServers                  []ICEServer       `json:"iceservers"`                    // STUN and TURN servers for media connections
+	ICEServersExpireAt          time.Time         `json:"iceservers_expire_at"`          // The time the ICE server credentials expire
+
+	Extra map[string]json.RawMessage `json:"-"` // Unknown configuration keys
+}
+
+// InfoStorage holds the names of the storage buckets used by Sqwiggle.
+type InfoStorage struct {
+	Avatars string `json:"avatars"`
+	Clients string `json:"clients"`
+	Uploads string `json:"uploads"`
+}
+
+// ICEServer is a STUN or TURN server that may be used to establish media
+// connections. Username and Credential are only set for TURN servers.
+type ICEServer struct {
+	URL        string `json:"url"`
+	Username   string `json:"username,omitempty"`
+	Credential string `json:"credential,omitempty"`
+}
+
+// Release describes the releases of a Sqwiggle app on one platform.
+type Release struct {
+	Current string `json:"current"` // The latest version
+	Minimum string `json:"minimum"` // The oldest version that is still supported
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown keys in Extra.
+func (i *Info) UnmarshalJSON(b []byte) error {
+	type info Info // has no methods, so does not recurse
+	if err := json.Unmarshal(b, (*info)(i)); err != nil {
+		return err
+	}
+	extra, err := unknownKeys(b, "configuration", "releases")
+	i.Extra = extra
+	return err
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown keys in Extra.
+func (c *InfoConfiguration) UnmarshalJSON(b []byte) error {
+	type configuration InfoConfiguration // has no methods, so does not recurse
+	if err := json.Unmarshal(b, (*configuration)(c)); err != nil {
+		return err
+	}
+	extra, err := unknownKeys(b, "storage", "commands", "max_upload_filesize", "max_conversation_participants",
+		"max_free_streams", "iceservers", "iceservers_expire_at")
+	c.Extra = extra
+	return err
+}
+
+// unknownKeys returns the keys of the JSON object b that are not in known,
+// or nil if there are none.
+func unknownKeys(b []byte, known ...string) (map[string]json.RawMessage, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	for _, k := range known {
+		delete(all, k)
+	}
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return all, nil
+}
@@ -0,0 +1,157 @@
+package sqwiggle
+
+import (
+	"context"
+	"iter"
+)
+
+// defaultPageSize is the number of items requested per page by the All*
+// iterators when PageOptions.PageSize is not set.
+const defaultPageSize = 50
+
+// PageOptions controls how the All* iterators page through a list
+// endpoint. A nil *PageOptions is equivalent to the zero value.
+type PageOptions struct {
+	PageSize  int // Number of items to request per page, defaults to 50
+	StartPage int // First page to fetch, defaults to 1
+	MaxItems  int // Stop after this many items, zero means no limit
+}
+
+// Paginate returns an iterator over all items returned by list, fetching
+// pages lazily as the caller consumes them. Iteration ends after the first
+// page that holds fewer items than requested, after opts.MaxItems items,
+// or after the first error, which is yielded together with the zero value
+// of T. No further pages are fetched once the caller stops iterating.
+//
+// Paginate backs the All* methods of Client, and can be used with any
+// function that has the signature of the List*Context methods.
+func Paginate[T any](ctx context.Context, opts *PageOptions, list func(ctx context.Context, page, limit int) ([]T, error)) iter.Seq2[T, error] {
+	var o PageOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = defaultPageSize
+	}
+	if o.StartPage <= 0 {
+		o.StartPage = 1
+	}
+
+	return func(yield func(T, error) bool) {
+		n := 0
+		for page := o.StartPage; ; page++ {
+			items, err := list(ctx, page, o.PageSize)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+				n++
+				if o.MaxItems > 0 && n >= o.MaxItems {
+					return
+				}
+			}
+			if len(items) < o.PageSize {
+				return
+			}
+		}
+	}
+}
+
+// AllMessages returns an iterator over all messages in the current
+// organization, as returned by ListMessages.
+func (c *Client) AllMessages(ctx context.Context, opts *PageOptions) iter.Seq2[Message, error] {
+	return Paginate(ctx, opts, c.ListMessagesContext)
+}
+
+// AllMessagesWithOptions returns an iterator over all messages that
+// match options, as returned by ListMessagesWithOptions. Since messages
+// are listed newest first, iteration ends at the first message older
+// than options.Since or options.AfterID. opts.MaxItems counts matching
+// messages.
+func (c *Client) AllMessagesWithOptions(ctx context.Context, opts *PageOptions, options *ListMessagesOptions) iter.Seq2[Message, error] {
+	var o PageOptions
+	if opts != nil {
+		o = *opts
+	}
+	max := o.MaxItems
+	o.MaxItems = 0
+	streamID := 0
+	if options != nil {
+		streamID = options.StreamID
+	}
+
+	return func(yield func(Message, error) bool) {
+		if err := options.Validate(); err != nil {
+			yield(Message{}, err)
+			return
+		}
+		// once the API has not provided the stream endpoint, stop asking
+		nested := true
+		list := func(ctx context.Context, page, limit int) ([]Message, error) {
+			msgs, ok, err := c.listMessagesPage(ctx, "AllMessagesWithOptions", streamID, nested, page, limit)
+			nested = ok
+			return msgs, err
+		}
+		n := 0
+		for m, err := range Paginate(ctx, &o, list) {
+			if err != nil {
+				yield(m, err)
+				return
+			}
+			if options.Past(m) {
+				return
+			}
+			if !options.Match(m) {
+				continue
+			}
+			if !yield(m, nil) {
+				return
+			}
+			n++
+			if max > 0 && n >= max {
+				return
+			}
+		}
+	}
+}
+
+// AllStreams returns an iterator over all streams in the current
+// organization, as returned by ListStreams.
+func (c *Client) AllStreams(ctx context.Context, opts *PageOptions) iter.Seq2[Stream, error] {
+	return Paginate(ctx, opts, c.ListStreamsContext)
+}
+
+// AllUsers returns an iterator over all users in the current
+// organization, as returned by ListUsers.
+func (c *Client) AllUsers(ctx context.Context, opts *PageOptions) iter.Seq2[User, error] {
+	return Paginate(ctx, opts, c.ListUsersContext)
+}
+
+// AllInvites returns an iterator over all outstanding invites in the
+// current organization, as returned by ListInvites.
+func (c *Client) AllInvites(ctx context.Context, opts *PageOptions) iter.Seq2[Invite, error] {
+	return Paginate(ctx, opts, c.ListInvitesContext)
+}
+
+// AllAttachments returns an iterator over all attachments in the current
+// organization, as returned by ListAttachments.
+func (c *Client) AllAttachments(ctx context.Context, opts *PageOptions) iter.Seq2[Attachment, error] {
+	return Paginate(ctx, opts, c.ListAttachmentsContext)
+}
+
+// AllConversations returns an iterator over all conversations the current
+// token has access to, as returned by ListConversations.
+func (c *Client) AllConversations(ctx context.Context, opts *PageOptions) iter.Seq2[Conversation, error] {
+	return Paginate(ctx, opts, c.ListConversationsContext)
+}
+
+// AllOrganizations returns an iterator over all organizations the current
+// token has access to, as returned by ListOrganizations.
+func (c *Client) AllOrganizations(ctx context.Context, opts *PageOptions) iter.Seq2[Organization, error] {
+	return Paginate(ctx, opts, c.ListOrganizationsContext)
+}
@@ -0,0 +1,203 @@
+package sqwiggle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// setupPagedServer returns a server that serves total streams at /streams,
+// paged according to the page and limit parameters, and records the pages
+// that were requested.
+func setupPagedServer(t *testing.T, total int) (*httptest.Server, *Client, *[]int) {
+	var pages []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		pages = append(pages, page)
+
+		streams := []Stream{}
+		for id := (page-1)*limit + 1; id <= page*limit && id <= total; id++ {
+			streams = append(streams, Stream{ID: id, Name: fmt.Sprintf("stream %d", id)})
+		}
+		if err := json.NewEncoder(w).Encode(streams); err != nil {
+			t.Error(err)
+		}
+	}))
+
+	client := NewClient("test")
+	client.RootURL = server.URL
+	return server, client, &pages
+}
+
+func TestAllStreams(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     int
+		opts      *PageOptions
+		wantIDs   int
+		wantPages []int
+	}{
+		{
+			name:      "stops on short page",
+			total:     7,
+			opts:      &PageOptions{PageSize: 3},
+			wantIDs:   7,
+			wantPages: []int{1, 2, 3},
+		},
+		{
+			name:      "stops on empty page",
+			total:     6,
+			opts:      &PageOptions{PageSize: 3},
+			wantIDs:   6,
+			wantPages: []int{1, 2, 3},
+		},
+		{
+			name:      "max items",
+			total:     100,
+			opts:      &PageOptions{PageSize: 3, MaxItems: 4},
+			wantIDs:   4,
+			wantPages: []int{1, 2},
+		},
+		{
+			name:      "default options",
+			total:     10,
+			opts:      nil,
+			wantIDs:   10,
+			wantPages: []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		server, client, pages := setupPagedServer(t, tt.total)
+
+		n := 0
+		for s, err := range client.AllStreams(context.Background(), tt.opts) {
+			if err != nil {
+				t.Fatalf("%q case: got error: %v", tt.name, err)
+			}
+			n++
+			if s.ID != n {
+				t.Errorf("%q case: stream #%d has ID %d", tt.name, n, s.ID)
+			}
+		}
+		if n != tt.wantIDs {
+			t.Errorf("%q case: got %d streams, want %d", tt.name, n, tt.wantIDs)
+		}
+		if fmt.Sprint(*pages) != fmt.Sprint(tt.wantPages) {
+			t.Errorf("%q case: fetched pages %v, want %v", tt.name, *pages, tt.wantPages)
+		}
+		server.Close()
+	}
+}
+
+// TestAllStreams_Break checks that no more pages are fetched once the
+// caller stops iterating.
+func TestAllStreams_Break(t *testing.T) {
+	server, client, pages := setupPagedServer(t, 100)
+	defer server.Close()
+
+	for s := range client.AllStreams(context.Background(), &PageOptions{PageSize: 5}) {
+		if s.ID == 5 {
+			break
+		}
+	}
+	if len(*pages) != 1 {
+		t.Errorf("fetched pages %v, want only page 1", *pages)
+	}
+}
+
+// TestAllMessages_Error checks that errors are yielded and end the
+// iteration.
+func TestAllMessages_Error(t *testing.T) {
+	server, client := setupTestServer(401, []byte(`{"type": "authentication"}`), func(r *http.Request) {})
+	defer server.Close()
+
+	n := 0
+	for _, err := range client.AllMessages(context.Background(), nil) {
+		n++
+		if err == nil {
+			t.Error("got nil error")
+		}
+	}
+	if n != 1 {
+		t.Errorf("got %d values, want 1", n)
+	}
+}
+
+func TestAllMessagesWithOptions(t *testing.T) {
+	// 10 messages, newest first, alternating between streams 1 and 2
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery)
+		if r.URL.Path == "/streams/1" {
+			json.NewEncoder(w).Encode(Stream{ID: 1})
+			return
+		}
+		if r.URL.Path != "/messages" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		msgs := []Message{}
+		for i := (page-1)*limit + 1; i <= page*limit && i <= 10; i++ {
+			id := 11 - i
+			msgs = append(msgs, Message{ID: id, StreamID: 1 + id%2})
+		}
+		json.NewEncoder(w).Encode(msgs)
+	}))
+	defer server.Close()
+	client := NewClient("test")
+	client.RootURL = server.URL
+
+	var got []int
+	for m, err := range client.AllMessagesWithOptions(context.Background(), &PageOptions{PageSize: 3}, &ListMessagesOptions{StreamID: 1, AfterID: 3}) {
+		if err != nil {
+			t.Fatal("got error:", err)
+		}
+		got = append(got, m.ID)
+	}
+	if want := []int{10, 8, 6, 4}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got messages %v, want %v", got, want)
+	}
+	// the stream endpoint is only tried once, and paging stops at AfterID
+	want := []string{"/streams/1/messages?limit=3&page=1", "/streams/1?", "/messages?limit=3&page=1", "/messages?limit=3&page=2", "/messages?limit=3&page=3"}
+	if fmt.Sprint(requests) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+}
+
+func TestAllMessagesWithOptions_MissingStream(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.URL.Path != "/messages" {
+			// neither the stream endpoint nor the stream exist
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]Message{{ID: 1, StreamID: 1}})
+	}))
+	defer server.Close()
+	client := NewClient("test")
+	client.RootURL = server.URL
+
+	n := 0
+	var err error
+	for _, err = range client.AllMessagesWithOptions(context.Background(), nil, &ListMessagesOptions{StreamID: 5}) {
+		n++
+	}
+	var apiErr *APIError
+	if n != 1 || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got %d values and error %v, want a single 404 error", n, err)
+	}
+	if want := []string{"/streams/5/messages", "/streams/5"}; fmt.Sprint(requests) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+}
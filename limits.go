@@ -0,0 +1,148 @@
+package sqwiggle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultLimitsTTL is how long a LimitsGuard caches the limits by default.
+const defaultLimitsTTL = time.Hour
+
+// Limits are the limits of the Sqwiggle service, as advertised by the
+// /info endpoint, that the client enforces. A zero value means there is
+// no limit.
+type Limits struct {
+	MaxUploadFilesize int64 // Maximum size of an upload in bytes
+	MaxFreeStreams    int   // Maximum number of streams an organization may create for free
+}
+
+// LimitsGuard makes a Client enforce the limits advertised by /info on
+// the client side, so that requests that would exceed them fail early,
+// without a round trip to the API. Set it on Client.Limits to enable it.
+// The limits are fetched when first needed, and cached for TTL. A
+// LimitsGuard is safe for concurrent use.
+type LimitsGuard struct {
+	TTL time.Duration // How long to cache the limits, defaults to an hour
+
+	mu       sync.Mutex
+	limits   Limits
+	fetched  time.Time
+	fetching chan struct{} // closed when the fetch in flight is done
+}
+
+// NewLimitsGuard returns a LimitsGuard with the default TTL.
+func NewLimitsGuard() *LimitsGuard {
+	return &LimitsGuard{TTL: defaultLimitsTTL}
+}
+
+// get returns the cached limits, fetching them with c if they are stale.
+// Concurrent callers share a single fetch, which is made without holding
+// the lock.
+func (g *LimitsGuard) get(ctx context.Context, c *Client) (Limits, error) {
+	ttl := g.TTL
+	if ttl <= 0 {
+		ttl = defaultLimitsTTL
+	}
+	for {
+		g.mu.Lock()
+		if !g.fetched.IsZero() && time.Since(g.fetched) < ttl {
+			limits := g.limits
+			g.mu.Unlock()
+			return limits, nil
+		}
+		if fetching := g.fetching; fetching != nil {
+			g.mu.Unlock()
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return Limits{}, ctx.Err()
+			}
+		}
+		fetching := make(chan struct{})
+		g.fetching = fetching
+		g.mu.Unlock()
+
+		limits, err := c.fetchLimits(ctx)
+		g.mu.Lock()
+		if err == nil {
+			g.limits, g.fetched = limits, time.Now()
+		}
+		g.fetching = nil
+		g.mu.Unlock()
+		close(fetching)
+		return limits, err
+	}
+}
+
+// Invalidate discards the cached limits, so that they are fetched again
+// when next needed.
+func (g *LimitsGuard) Invalidate() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.fetched = time.Time{}
+}
+
+// fetchLimits gets the current limits from /info.
+func (c *Client) fetchLimits(ctx context.Context) (Limits, error) {
+	info, err := c.GetInfoTypedContext(ctx)
+	if err != nil {
+		return Limits{}, err
+	}
+	return Limits{
+		MaxUploadFilesize: info.Configuration.MaxUploadFilesize,
+		MaxFreeStreams:    info.Configuration.MaxFreeStreams,
+	}, nil
+}
+
+// limits returns the current limits, from the client's LimitsGuard if it
+// has one.
+func (c *Client) limits(ctx context.Context) (Limits, error) {
+	if c.Limits != nil {
+		return c.Limits.get(ctx, c)
+	}
+	return c.fetchLimits(ctx)
+}
+
+// checkStreamLimit returns an ErrLimitReached error if the organization
+// cannot create any more free streams.
+func (c *Client) checkStreamLimit(ctx context.Context) error {
+	limits, err := c.limits(ctx)
+	if err != nil || limits.MaxFreeStreams <= 0 {
+		return err
+	}
+	n := 0
+	for _, err := range c.AllStreams(ctx, &PageOptions{MaxItems: limits.MaxFreeStreams}) {
+		if err != nil {
+			return err
+		}
+		n++
+	}
+	if n >= limits.MaxFreeStreams {
+		return Error{
+			Type:    ErrLimitReached,
+			Message: fmt.Sprintf("The organization already has the maximum of %d free streams", limits.MaxFreeStreams),
+		}
+	}
+	return nil
+}
+
+// CheckUpload returns an ErrLimitReached error if an upload of size bytes
+// exceeds the max_upload_filesize advertised by /info. The limit is taken
+// from the client's LimitsGuard if it has one, and fetched otherwise.
+func (c *Client) CheckUpload(ctx context.Context, size int64) error {
+	limits, err := c.limits(ctx)
+	if err != nil {
+		return err
+	}
+	if limits.MaxUploadFilesize > 0 && size > limits.MaxUploadFilesize {
+		return Error{
+			Type:    ErrLimitReached,
+			Message: fmt.Sprintf("The upload of %d bytes exceeds the maximum file size of %d bytes", size, limits.MaxUploadFilesize),
+			Param:   "size",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package sqwiggle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// setupLimitsServer returns a server that serves testdata/info.json, which
+// allows 5 free streams, and lists the given number of streams.
+func setupLimitsServer(t *testing.T, streams int) (*httptest.Server, *Client, map[string]int) {
+	info, err := ioutil.ReadFile("testdata/info.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.Method+" "+r.URL.Path]++
+		switch r.URL.Path {
+		case "/info":
+			w.Write(info)
+		case "/streams":
+			if r.Method == "POST" {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": 1, "name": "new"}`))
+				return
+			}
+			list := "["
+			for i := 1; i <= streams; i++ {
+				if i > 1 {
+					list += ","
+				}
+				list += fmt.Sprintf(`{"id": %d}`, i)
+			}
+			w.Write([]byte(list + "]"))
+		}
+	}))
+	client := NewClient("test")
+	client.RootURL = server.URL
+	client.Limits = NewLimitsGuard()
+	return server, client, calls
+}
+
+func Test_Limits_PostStream(t *testing.T) {
+	server, client, calls := setupLimitsServer(t, 5)
+	defer server.Close()
+
+	_, err := client.PostStream("one too many")
+	if !errors.Is(err, ErrLimitReached) {
+		t.Fatalf("err = %v, want %q", err, ErrLimitReached)
+	}
+	if calls["POST /streams"] != 0 {
+		t.Error("stream was posted despite the limit")
+	}
+
+	// the limits are cached
+	client.PostStream("still too many")
+	if calls["GET /info"] != 1 {
+		t.Errorf("GET /info called %d times, want %d", calls["GET /info"], 1)
+	}
+}
+
+func Test_Limits_PostStream_Allowed(t *testing.T) {
+	server, client, calls := setupLimitsServer(t, 4)
+	defer server.Close()
+
+	if _, err := client.PostStream("last one"); err != nil {
+		t.Fatal("got error:", err)
+	}
+	if calls["POST /streams"] != 1 {
+		t.Errorf("POST /streams called %d times, want %d", calls["POST /streams"], 1)
+	}
+
+	// without a guard, nothing is checked
+	client.Limits = nil
+	if _, err := client.PostStream("another"); err != nil {
+		t.Fatal("got error:", err)
+	}
+	if calls["GET /info"] != 1 {
+		t.Errorf("GET /info called %d times, want %d", calls["GET /info"], 1)
+	}
+}
+
+func Test_Limits_CheckUpload(t *testing.T) {
+	server, client, calls := setupLimitsServer(t, 0)
+	defer server.Close()
+
+	if err := client.CheckUpload(context.Background(), 26214400); err != nil {
+		t.Errorf("CheckUpload(max) = %v, want nil", err)
+	}
+	err := client.CheckUpload(context.Background(), 26214401)
+	if !errors.Is(err, ErrLimitReached) {
+		t.Errorf("CheckUpload(max+1) = %v, want %q", err, ErrLimitReached)
+	}
+	if n := calls["GET /info"]; n != 1 {
+		t.Errorf("/info fetched %d times, want %d", n, 1)
+	}
+
+	// without a LimitsGuard, the limits are fetched every time
+	client.Limits = nil
+	if err := client.CheckUpload(context.Background(), 26214401); !errors.Is(err, ErrLimitReached) {
+		t.Errorf("CheckUpload(max+1) = %v, want %q", err, ErrLimitReached)
+	}
+	if n := calls["GET /info"]; n != 2 {
+		t.Errorf("/info fetched %d times, want %d", n, 2)
+	}
+}
+
+// Test_Limits_Fetch checks that concurrent callers share a single fetch of
+// /info, and that the guard is not locked while it is in flight.
+func Test_Limits_Fetch(t *testing.T) {
+	server, client, calls := setupLimitsServer(t, 0)
+	defer server.Close()
+	var fetches int32
+	started, release := make(chan struct{}), make(chan struct{})
+	client.Middleware = append(client.Middleware, func(next Handler) Handler {
+		return func(op string, req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/info" && atomic.AddInt32(&fetches, 1) == 1 {
+				close(started)
+				<-release
+			}
+			return next(op, req)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limits, err := client.limits(context.Background())
+			if err != nil || limits.MaxFreeStreams != 5 {
+				t.Errorf("limits = %+v, %v, want 5 free streams", limits, err)
+			}
+		}()
+	}
+	<-started
+	client.Limits.Invalidate()
+	close(release)
+	wg.Wait()
+	if n := calls["GET /info"]; n != 1 {
+		t.Errorf("/info fetched %d times, want %d", n, 1)
+	}
+}
@@ -0,0 +1,98 @@
+package sqwiggle
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// redacted replaces secrets in log output.
+const redacted = "[REDACTED]"
+
+// secretFields matches JSON fields holding secrets that may appear in
+// request or response bodies, such as the TURN credentials in /info.
+var secretFields = regexp.MustCompile(`("(?:credential|username|password|auth_token|api_key)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// LogValue implements slog.LogValuer, so that logging a Client never
+// reveals its API key.
+func (c *Client) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("root_url", c.RootURL),
+		slog.String("api_key", redacted),
+	)
+}
+
+// redact removes the API key and any secret fields from s.
+func (c *Client) redact(s string) string {
+	if c.APIKey != "" {
+		s = strings.ReplaceAll(s, c.APIKey, redacted)
+	}
+	return secretFields.ReplaceAllString(s, `$1"`+redacted+`"`)
+}
+
+// logOperation logs the outcome of the API operation op, whose last
+// request was req.
+func (c *Client) logOperation(ctx context.Context, op string, req *http.Request, resp *response, err error, d time.Duration) {
+	if req == nil {
+		// the request could not even be built
+		c.Logger.LogAttrs(ctx, slog.LevelError, "sqwiggle: request failed",
+			slog.String("op", op), slog.String("error", c.redact(err.Error())))
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("op", op),
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.Duration("duration", d),
+	}
+	q := req.URL.Query()
+	if page := q.Get("page"); page != "" {
+		attrs = append(attrs, slog.String("page", page))
+	}
+	if limit := q.Get("limit"); limit != "" {
+		attrs = append(attrs, slog.String("limit", limit))
+	}
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.statusCode))
+	}
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) {
+		attrs = append(attrs, slog.Int("attempts", retryErr.Attempts))
+	}
+
+	level, msg := slog.LevelInfo, "sqwiggle: request"
+	if err == nil && resp != nil && resp.statusCode >= 400 {
+		// the caller turns unexpected responses into errors
+		err = resp.err()
+	}
+	if err != nil {
+		level, msg = slog.LevelWarn, "sqwiggle: request failed"
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			attrs = append(attrs, slog.String("error_type", apiErr.Err.Type.String()))
+		}
+		attrs = append(attrs, slog.String("error", c.redact(err.Error())))
+	}
+	c.Logger.LogAttrs(ctx, level, msg, attrs...)
+
+	if !c.Logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	debug := []slog.Attr{slog.String("op", op)}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := ioutil.ReadAll(body)
+			debug = append(debug, slog.String("request_body", c.redact(string(b))))
+		}
+	}
+	if resp != nil {
+		debug = append(debug, slog.String("response_body", c.redact(string(resp.body))))
+	}
+	c.Logger.LogAttrs(ctx, slog.LevelDebug, "sqwiggle: request bodies", debug...)
+}
@@ -0,0 +1,93 @@
+package sqwiggle
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Test_Logger_Operation checks that operations are logged with their
+// request details.
+func Test_Logger_Operation(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/liststreams.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, client := setupTestServer(200, dummy, func(r *http.Request) {})
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+
+	_, err = client.ListStreams(2, 10)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"op=ListStreams", "method=GET", "path=/streams", "status=200", "page=2", "limit=10", "duration="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output %q does not contain %q", out, want)
+		}
+	}
+	if strings.Contains(out, "request_body") {
+		t.Errorf("log output %q contains bodies, want them only at debug level", out)
+	}
+}
+
+// Test_Logger_Error checks that the error type of failed operations is
+// logged.
+func Test_Logger_Error(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/error.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, client := setupTestServer(401, dummy, func(r *http.Request) {})
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+
+	client.DeleteStream(1)
+
+	out := buf.String()
+	for _, want := range []string{"level=WARN", "op=DeleteStream", "status=401", "error_type=authentication"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output %q does not contain %q", out, want)
+		}
+	}
+}
+
+// Test_Logger_Redact checks that neither the API key nor the TURN
+// credentials from /info make it into debug output.
+func Test_Logger_Redact(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/info.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, client := setupTestServer(200, dummy, func(r *http.Request) {})
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client.APIKey = "super-secret-key"
+	client.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client.Logger.Info("client", "client", client)
+
+	_, err = client.GetInfo()
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "response_body") {
+		t.Errorf("log output %q does not contain the response body", out)
+	}
+	for _, secret := range []string{"super-secret-key", "5f4dcc3b5aa765d61d8327deb882cf99", "2uJpitJ08aSb7uWmjQ93c8/0KWI3SzWiVmTWTg1W7Pg="} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output contains secret %q", secret)
+		}
+	}
+}
@@ -1,5 +1,12 @@
 package sqwiggle
 
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
 // Mention represents a mention as part of a message in any chat stream.
 type Mention struct {
 	ID          int      `json:"id"`
@@ -10,3 +17,175 @@ type Mention struct {
 	SubjectType UserType `json:"subject_type"`
 	SubjectID   int      `json:"subject_id"`
 }
+
+// FormatMention returns the token that mentions the user with the given
+// name and ID in the text of a posted message, @(user_name)[user:user_id].
+// Parentheses, brackets and backslashes in the name are escaped with a
+// backslash.
+func FormatMention(name string, id int) string {
+	return "@(" + EscapeMentionName(name) + ")[user:" + strconv.Itoa(id) + "]"
+}
+
+// MentionToken returns the token that mentions u in the text of a posted
+// message, see FormatMention.
+func (u User) MentionToken() string {
+	return FormatMention(u.Name, u.ID)
+}
+
+// EscapeMentionName escapes the characters of name that would end the
+// name part of a mention token early.
+func EscapeMentionName(name string) string {
+	if !strings.ContainsAny(name, `\()[]`) {
+		return name
+	}
+	var b strings.Builder
+	for _, r := range name {
+		switch r {
+		case '\\', '(', ')', '[', ']':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Segment is a part of the text of a message: either plain text, or a
+// mention of a user.
+type Segment struct {
+	Text    string   // The text of the segment, for mentions the name of the mentioned user
+	Mention *Mention // The mention, nil for plain text
+}
+
+// ParseMentions splits the text of a message as it is posted into plain
+// text and the mentions in it, which use the @(user_name)[user:user_id]
+// syntax. Text that only looks like the start of a mention is kept as
+// plain text.
+func ParseMentions(text string) []Segment {
+	var segs []Segment
+	plain := 0 // start of the current plain text
+	for i := 0; i < len(text); {
+		j := strings.Index(text[i:], "@(")
+		if j < 0 {
+			break
+		}
+		start := i + j
+		name, id, end, ok := parseMentionToken(text, start)
+		if !ok {
+			i = start + 1
+			continue
+		}
+		if plain < start {
+			segs = append(segs, Segment{Text: text[plain:start]})
+		}
+		segs = append(segs, Segment{Text: name, Mention: &Mention{
+			Name:        name,
+			Text:        name,
+			SubjectType: TypeUser,
+			SubjectID:   id,
+		}})
+		plain, i = end, end
+	}
+	if plain < len(text) {
+		segs = append(segs, Segment{Text: text[plain:]})
+	}
+	return segs
+}
+
+// parseMentionToken parses the mention token at text[start:], and returns
+// the unescaped name, the user ID and the end of the token.
+func parseMentionToken(text string, start int) (name string, id int, end int, ok bool) {
+	var b strings.Builder
+	i := start + len("@(")
+	for ; i < len(text) && text[i] != ')'; i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			i++
+		}
+		b.WriteByte(text[i])
+	}
+	const prefix = ")[user:"
+	if !strings.HasPrefix(text[i:], prefix) {
+		return "", 0, 0, false
+	}
+	i += len(prefix)
+	digits := i
+	for i < len(text) && text[i] >= '0' && text[i] <= '9' {
+		i++
+	}
+	if i == digits || i == len(text) || text[i] != ']' {
+		return "", 0, 0, false
+	}
+	id, err := strconv.Atoi(text[digits:i])
+	if err != nil {
+		return "", 0, 0, false
+	}
+	return b.String(), id, i + 1, true
+}
+
+// FormatSegments joins segments into the text of a message to be posted,
+// formatting mentions as tokens. It is the inverse of ParseMentions.
+func FormatSegments(segs []Segment) string {
+	var b strings.Builder
+	for _, s := range segs {
+		if s.Mention != nil {
+			b.WriteString(FormatMention(s.Text, s.Mention.SubjectID))
+		} else {
+			b.WriteString(s.Text)
+		}
+	}
+	return b.String()
+}
+
+// RenderSegments joins segments into the text of a message as the API
+// returns it, with mentions replaced by the names of the mentioned users,
+// and returns the mentions with their Indices set to the rune offsets of
+// the names in the text.
+func RenderSegments(segs []Segment) (string, []Mention) {
+	var b strings.Builder
+	mentions := []Mention{}
+	n := 0 // runes written
+	for _, s := range segs {
+		b.WriteString(s.Text)
+		l := utf8.RuneCountInString(s.Text)
+		if s.Mention != nil {
+			m := *s.Mention
+			m.Text = s.Text
+			m.Indices = []int{n, n + l}
+			mentions = append(mentions, m)
+		}
+		n += l
+	}
+	return b.String(), mentions
+}
+
+// Segments splits the text of the message, as returned by the API, into
+// plain text and mentions, using the Indices of its Mentions. Mentions
+// whose indices are out of range or overlap an earlier mention are left
+// as plain text.
+func (m Message) Segments() []Segment {
+	mentions := make([]Mention, 0, len(m.Mentions))
+	for _, mention := range m.Mentions {
+		if len(mention.Indices) == 2 {
+			mentions = append(mentions, mention)
+		}
+	}
+	sort.SliceStable(mentions, func(i, j int) bool { return mentions[i].Indices[0] < mentions[j].Indices[0] })
+
+	text := []rune(m.Text)
+	var segs []Segment
+	plain := 0
+	for i := range mentions {
+		start, end := mentions[i].Indices[0], mentions[i].Indices[1]
+		if start < plain || end < start || end > len(text) {
+			continue
+		}
+		if plain < start {
+			segs = append(segs, Segment{Text: string(text[plain:start])})
+		}
+		segs = append(segs, Segment{Text: string(text[start:end]), Mention: &mentions[i]})
+		plain = end
+	}
+	if plain < len(text) {
+		segs = append(segs, Segment{Text: string(text[plain:])})
+	}
+	return segs
+}
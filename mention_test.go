@@ -0,0 +1,85 @@
+package sqwiggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatMention(t *testing.T) {
+	tests := []struct {
+		name string
+		id   int
+		want string
+	}{
+		{"trin", 1, "@(trin)[user:1]"},
+		{"Jane (JD) Doe", 7, `@(Jane \(JD\) Doe)[user:7]`},
+		{`a[b]\c`, 2, `@(a\[b\]\\c)[user:2]`},
+	}
+	for _, tt := range tests {
+		if got := FormatMention(tt.name, tt.id); got != tt.want {
+			t.Errorf("FormatMention(%q, %d) = %q, want %q", tt.name, tt.id, got, tt.want)
+		}
+		segs := ParseMentions(tt.want)
+		if len(segs) != 1 || segs[0].Text != tt.name || segs[0].Mention.SubjectID != tt.id {
+			t.Errorf("ParseMentions(%q) = %+v, want a mention of %q", tt.want, segs, tt.name)
+		}
+	}
+
+	u := User{ID: 3, Name: "Test User"}
+	if got := u.MentionToken(); got != "@(Test User)[user:3]" {
+		t.Errorf("MentionToken() = %q, want %q", got, "@(Test User)[user:3]")
+	}
+}
+
+func TestParseMentions(t *testing.T) {
+	text := "This is a test, @(trin)[user:4]! Not @(a mention) or @(this)[user:x] @(Zoë)[user:5]"
+	segs := ParseMentions(text)
+	want := []Segment{
+		{Text: "This is a test, "},
+		{Text: "trin", Mention: &Mention{Name: "trin", Text: "trin", SubjectType: TypeUser, SubjectID: 4}},
+		{Text: "! Not @(a mention) or @(this)[user:x] "},
+		{Text: "Zoë", Mention: &Mention{Name: "Zoë", Text: "Zoë", SubjectType: TypeUser, SubjectID: 5}},
+	}
+	if !reflect.DeepEqual(segs, want) {
+		t.Errorf("ParseMentions(%q) = %+v, want %+v", text, segs, want)
+	}
+	if got := FormatSegments(segs); got != text {
+		t.Errorf("FormatSegments = %q, want %q", got, text)
+	}
+
+	rendered, mentions := RenderSegments(segs)
+	if want := "This is a test, trin! Not @(a mention) or @(this)[user:x] Zoë"; rendered != want {
+		t.Errorf("rendered text = %q, want %q", rendered, want)
+	}
+	if len(mentions) != 2 || !reflect.DeepEqual(mentions[0].Indices, []int{16, 20}) || !reflect.DeepEqual(mentions[1].Indices, []int{58, 61}) {
+		t.Errorf("mentions = %+v, want indices [16 20] and [58 61]", mentions)
+	}
+
+	// the segments of the rendered message are the parsed segments
+	m := Message{Text: rendered, Mentions: []Mention{mentions[1], mentions[0]}}
+	got := m.Segments()
+	for i := range got {
+		if got[i].Mention != nil {
+			got[i].Mention.Indices = nil
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Segments() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMessage_Segments(t *testing.T) {
+	m := Message{
+		Text: "This is a test, trin",
+		Mentions: []Mention{
+			{SubjectID: 1, Indices: []int{16, 20}},
+			{SubjectID: 2, Indices: []int{18, 25}}, // out of range
+			{SubjectID: 3, Indices: []int{17, 19}}, // overlaps the first
+			{SubjectID: 4},                         // no indices
+		},
+	}
+	segs := m.Segments()
+	if len(segs) != 2 || segs[0].Text != "This is a test, " || segs[1].Text != "trin" || segs[1].Mention.SubjectID != 1 {
+		t.Errorf("Segments() = %+v, want plain text and a mention of trin", segs)
+	}
+}
@@ -17,3 +17,13 @@ type Message struct {
 	// Undocumented
 	ConversationID *int `json:"conversation_id,omitempty"`
 }
+
+// RemovedText is the text that replaces the text of a message when it is
+// deleted, see Client.DeleteMessage.
+const RemovedText = "This message has been removed"
+
+// Removed reports whether the message has been deleted, and only remains
+// in the stream as a "This message has been removed" note.
+func (m Message) Removed() bool {
+	return m.Text == RemovedText
+}
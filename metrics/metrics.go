@@ -0,0 +1,231 @@
+// Package metrics collects metrics about the requests made by a
+// sqwiggle.Client and exposes them in the Prometheus text exposition
+// format.
+//
+// A Collector is attached to a Client as middleware, and is itself an
+// http.Handler that serves the collected metrics:
+//
+//	collector := metrics.NewCollector()
+//	collector.Instrument(client)
+//	http.Handle("/metrics", collector)
+//
+// All metrics are labelled with the name of the API operation, such as
+// "ListStreams" or "DeleteInvite".
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hermanschaaf/sqwiggle"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets of the
+// request latency histogram.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// errorTypeNetwork is the error type label used for requests that failed
+// without a response from the API.
+const errorTypeNetwork = "network"
+
+// Collector collects metrics about the requests made by one or more
+// Clients. It is safe for concurrent use.
+type Collector struct {
+	buckets []float64
+
+	mu         sync.Mutex
+	operations map[string]*operation
+}
+
+// operation holds the metrics of a single API operation.
+type operation struct {
+	requests map[int]uint64    // requests by HTTP status code, 0 for network errors
+	errors   map[string]uint64 // errors by error type
+	retries  uint64
+
+	buckets []uint64 // cumulative histogram counts, one per bucket
+	count   uint64
+	sum     float64
+}
+
+// NewCollector returns a Collector that uses DefaultBuckets for its
+// latency histogram.
+func NewCollector() *Collector {
+	return NewCollectorWithBuckets(DefaultBuckets)
+}
+
+// NewCollectorWithBuckets returns a Collector that uses the given upper
+// bounds, in seconds, for the buckets of its latency histogram.
+func NewCollectorWithBuckets(buckets []float64) *Collector {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &Collector{
+		buckets:    b,
+		operations: make(map[string]*operation),
+	}
+}
+
+// Instrument adds the Collector's middleware to client.
+func (c *Collector) Instrument(client *sqwiggle.Client) {
+	client.Middleware = append(client.Middleware, c.Middleware())
+}
+
+// Middleware returns a sqwiggle.Middleware that records every request
+// passing through it. Every attempt of a retried request is counted as a
+// request, and every attempt after the first is also counted as a retry.
+func (c *Collector) Middleware() sqwiggle.Middleware {
+	return func(next sqwiggle.Handler) sqwiggle.Handler {
+		return func(op string, req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(op, req)
+			d := time.Since(start)
+
+			status, errType := 0, ""
+			switch {
+			case err != nil:
+				errType = errorTypeNetwork
+			case resp.StatusCode >= 400:
+				status = resp.StatusCode
+				errType = errorType(resp)
+			default:
+				status = resp.StatusCode
+			}
+			c.observe(op, status, errType, sqwiggle.Attempt(req.Context()) > 1, d)
+			return resp, err
+		}
+	}
+}
+
+// errorType returns the type of the API error in resp, leaving the body
+// intact for the Client to read.
+func errorType(resp *http.Response) string {
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	var apiErr sqwiggle.Error
+	if err != nil || json.Unmarshal(b, &apiErr) != nil || apiErr.Type == "" {
+		return sqwiggle.ErrUnknown.String()
+	}
+	return apiErr.Type.String()
+}
+
+// observe records a single request.
+func (c *Collector) observe(op string, status int, errType string, retry bool, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, ok := c.operations[op]
+	if !ok {
+		o = &operation{
+			requests: make(map[int]uint64),
+			errors:   make(map[string]uint64),
+			buckets:  make([]uint64, len(c.buckets)),
+		}
+		c.operations[op] = o
+	}
+
+	o.requests[status]++
+	if errType != "" {
+		o.errors[errType]++
+	}
+	if retry {
+		o.retries++
+	}
+	secs := d.Seconds()
+	for i, le := range c.buckets {
+		if secs <= le {
+			o.buckets[i]++
+		}
+	}
+	o.count++
+	o.sum += secs
+}
+
+// ServeHTTP serves the collected metrics in the Prometheus text
+// exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// WriteTo writes the collected metrics to w in the Prometheus text
+// exposition format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ops := make([]string, 0, len(c.operations))
+	for op := range c.operations {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	var b strings.Builder
+
+	b.WriteString("# HELP sqwiggle_requests_total Total number of HTTP requests made to the Sqwiggle API.\n")
+	b.WriteString("# TYPE sqwiggle_requests_total counter\n")
+	for _, op := range ops {
+		o := c.operations[op]
+		codes := make([]int, 0, len(o.requests))
+		for code := range o.requests {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&b, "sqwiggle_requests_total{operation=%q,code=%q} %d\n", op, codeLabel(code), o.requests[code])
+		}
+	}
+
+	b.WriteString("# HELP sqwiggle_errors_total Total number of failed requests to the Sqwiggle API, by error type.\n")
+	b.WriteString("# TYPE sqwiggle_errors_total counter\n")
+	for _, op := range ops {
+		o := c.operations[op]
+		types := make([]string, 0, len(o.errors))
+		for t := range o.errors {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		for _, t := range types {
+			fmt.Fprintf(&b, "sqwiggle_errors_total{operation=%q,type=%q} %d\n", op, t, o.errors[t])
+		}
+	}
+
+	b.WriteString("# HELP sqwiggle_retries_total Total number of retried requests to the Sqwiggle API.\n")
+	b.WriteString("# TYPE sqwiggle_retries_total counter\n")
+	for _, op := range ops {
+		fmt.Fprintf(&b, "sqwiggle_retries_total{operation=%q} %d\n", op, c.operations[op].retries)
+	}
+
+	b.WriteString("# HELP sqwiggle_request_duration_seconds Latency of HTTP requests made to the Sqwiggle API.\n")
+	b.WriteString("# TYPE sqwiggle_request_duration_seconds histogram\n")
+	for _, op := range ops {
+		o := c.operations[op]
+		for i, le := range c.buckets {
+			fmt.Fprintf(&b, "sqwiggle_request_duration_seconds_bucket{operation=%q,le=%q} %d\n", op, strconv.FormatFloat(le, 'g', -1, 64), o.buckets[i])
+		}
+		fmt.Fprintf(&b, "sqwiggle_request_duration_seconds_bucket{operation=%q,le=\"+Inf\"} %d\n", op, o.count)
+		fmt.Fprintf(&b, "sqwiggle_request_duration_seconds_sum{operation=%q} %s\n", op, strconv.FormatFloat(o.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "sqwiggle_request_duration_seconds_count{operation=%q} %d\n", op, o.count)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// codeLabel returns the value of the code label for a status code.
+func codeLabel(code int) string {
+	if code == 0 {
+		return errorTypeNetwork
+	}
+	return strconv.Itoa(code)
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hermanschaaf/sqwiggle"
+)
+
+func TestCollector(t *testing.T) {
+	calls := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch {
+		case r.URL.Path == "/streams":
+			w.Write([]byte("[]"))
+		case r.URL.Path == "/invites/1" && calls < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/invites/1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type": "authentication", "message": "go away"}`))
+		}
+	}))
+	defer api.Close()
+
+	client := sqwiggle.NewClient("test")
+	client.RootURL = api.URL
+	client.Retry = sqwiggle.DefaultRetryPolicy()
+	client.Retry.BaseDelay = time.Millisecond
+
+	collector := NewCollectorWithBuckets([]float64{1, 0.5})
+	collector.Instrument(client)
+
+	if _, err := client.ListStreams(0, 0); err != nil {
+		t.Fatal("got error:", err)
+	}
+	if err := client.DeleteInvite(1); err != nil {
+		t.Fatal("got error:", err)
+	}
+	if _, err := client.GetUser(1); err == nil {
+		t.Fatal("got nil error")
+	}
+
+	// scrape the metrics over HTTP
+	server := httptest.NewServer(collector)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(b)
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want Prometheus text format", ct)
+	}
+	for _, want := range []string{
+		`sqwiggle_requests_total{operation="ListStreams",code="200"} 1`,
+		`sqwiggle_requests_total{operation="DeleteInvite",code="204"} 1`,
+		`sqwiggle_requests_total{operation="DeleteInvite",code="503"} 1`,
+		`sqwiggle_errors_total{operation="DeleteInvite",type="unknown"} 1`,
+		`sqwiggle_errors_total{operation="GetUser",type="authentication"} 1`,
+		`sqwiggle_retries_total{operation="DeleteInvite"} 1`,
+		`sqwiggle_retries_total{operation="ListStreams"} 0`,
+		`sqwiggle_request_duration_seconds_bucket{operation="ListStreams",le="0.5"} 1`,
+		`sqwiggle_request_duration_seconds_bucket{operation="ListStreams",le="1"} 1`,
+		`sqwiggle_request_duration_seconds_bucket{operation="ListStreams",le="+Inf"} 1`,
+		`sqwiggle_request_duration_seconds_count{operation="DeleteInvite"} 2`,
+		"# TYPE sqwiggle_request_duration_seconds histogram",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+// TestCollector_KeepsErrorBody checks that the Client still sees the API
+// error after the Collector has inspected the response.
+func TestCollector_KeepsErrorBody(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"type": "validation", "message": "bad name"}`))
+	}))
+	defer api.Close()
+
+	client := sqwiggle.NewClient("test")
+	client.RootURL = api.URL
+	NewCollector().Instrument(client)
+
+	_, err := client.PostStream("")
+	if err == nil || err.(*sqwiggle.APIError).Err.Message != "bad name" {
+		t.Errorf("err = %v, want validation error with message %q", err, "bad name")
+	}
+}
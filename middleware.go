@@ -0,0 +1,45 @@
+package sqwiggle
+
+import "net/http"
+
+// Handler performs the HTTP round trip for a request made on behalf of
+// the API operation op. Operations are named after the Client method that
+// makes the request, such as "ListStreams" or "PostMessage", without the
+// Context suffix.
+type Handler func(op string, req *http.Request) (*http.Response, error)
+
+// Middleware wraps a Handler to add behaviour to every request made by a
+// Client, such as logging, metrics, adding headers or signing requests.
+// A middleware may modify the request before passing it on to next,
+// modify the response returned by next, or return a response of its own
+// without calling next at all.
+//
+// Middleware runs once for every attempt, so a request that is retried
+// passes through it several times. Any response a middleware returns must
+// have a non-nil Body, which the Client closes.
+type Middleware func(next Handler) Handler
+
+// handler returns the Handler that sends requests for this client: its
+// HTTPClient, wrapped in its Middleware.
+func (c *Client) handler() Handler {
+	h := func(op string, req *http.Request) (*http.Response, error) {
+		return c.HTTPClient.Do(req)
+	}
+	for i := len(c.Middleware) - 1; i >= 0; i-- {
+		h = c.Middleware[i](h)
+	}
+	return h
+}
+
+// HeaderMiddleware returns a Middleware that sets the given headers on
+// every request.
+func HeaderMiddleware(header http.Header) Middleware {
+	return func(next Handler) Handler {
+		return func(op string, req *http.Request) (*http.Response, error) {
+			for k, v := range header {
+				req.Header[k] = v
+			}
+			return next(op, req)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package sqwiggle
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Test_Middleware_Order checks that middleware sees the operation name and
+// runs in the order it was added.
+func Test_Middleware_Order(t *testing.T) {
+	var gotHeader string
+	server, client := setupTestServer(204, []byte{}, func(r *http.Request) {
+		gotHeader = r.Header.Get("X-Test")
+	})
+	defer server.Close()
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(op string, req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" "+op)
+				return next(op, req)
+			}
+		}
+	}
+	client.Middleware = []Middleware{
+		trace("first"),
+		HeaderMiddleware(http.Header{"X-Test": {"hello"}}),
+		trace("second"),
+	}
+
+	err := client.DeleteInvite(1)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+
+	want := "[first DeleteInvite second DeleteInvite]"
+	if got := fmt.Sprint(calls); got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+	if gotHeader != "hello" {
+		t.Errorf("X-Test header = %q, want %q", gotHeader, "hello")
+	}
+}
+
+// Test_Middleware_ShortCircuit checks that a middleware can answer a
+// request without it reaching the server.
+func Test_Middleware_ShortCircuit(t *testing.T) {
+	called := false
+	server, client := setupTestServer(200, []byte("{}"), func(r *http.Request) {
+		called = true
+	})
+	defer server.Close()
+
+	// inject a fault for PostMessage only
+	client.Middleware = []Middleware{
+		func(next Handler) Handler {
+			return func(op string, req *http.Request) (*http.Response, error) {
+				if op != "PostMessage" {
+					return next(op, req)
+				}
+				return &http.Response{
+					StatusCode: http.StatusUnprocessableEntity,
+					Header:     http.Header{},
+					Body:       ioutil.NopCloser(strings.NewReader(`{"type": "validation", "message": "nope"}`)),
+				}, nil
+			}
+		},
+	}
+
+	_, err := client.PostMessage(1, "hello", nil)
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("err = %v, want error of type %q", err, ErrValidation)
+	}
+	if called {
+		t.Error("request reached the server, want it short-circuited")
+	}
+}
@@ -0,0 +1,72 @@
+package sqwiggle
+
+import "net/url"
+
+// String returns a pointer to v, for setting the optional string fields
+// of option structs such as UpdateUserOptions.
+func String(v string) *string { return &v }
+
+// Int returns a pointer to v, for setting the optional int fields of
+// option structs such as UpdateUserOptions.
+func Int(v int) *int { return &v }
+
+// Status returns a pointer to v, for setting UpdateUserOptions.Status.
+func Status(v UserStatus) *UserStatus { return &v }
+
+// setString sets the parameter key of form to *v, if v is not nil.
+func setString(form url.Values, key string, v *string) {
+	if v != nil {
+		form.Set(key, *v)
+	}
+}
+
+// invalidParam returns an ErrInvalidParam error for options that are
+// found to be invalid before they are sent to the API.
+func invalidParam(param, message string) error {
+	return Error{Type: ErrInvalidParam, Message: message, Param: param}
+}
+
+// DiffAttachment returns the options that update the attachment old to
+// new, setting only the fields that can be updated and differ between the
+// two. It returns nil if there is nothing to update.
+func DiffAttachment(old, new Attachment) *UpdateAttachmentOptions {
+	diff, _ := compare(old, new)
+	var o UpdateAttachmentOptions
+	fields := map[string]**string{
+		"Title":       &o.Title,
+		"Description": &o.Description,
+		"URL":         &o.URL,
+		"Image":       &o.Image,
+		"Status":      &o.Status,
+	}
+	if !setChanged(diff, fields) {
+		return nil
+	}
+	return &o
+}
+
+// DiffOrganization returns the options that update the organization old
+// to new, setting only the fields that can be updated and differ between
+// the two. It returns nil if there is nothing to update.
+func DiffOrganization(old, new Organization) *UpdateOrganizationOptions {
+	diff, _ := compare(old, new)
+	var o UpdateOrganizationOptions
+	if !setChanged(diff, map[string]**string{"Name": &o.Name}) {
+		return nil
+	}
+	return &o
+}
+
+// setChanged points the option fields, keyed by the name of the struct
+// field they update, at the new values of the fields in diff. It reports
+// whether any option was set.
+func setChanged(diff difference, fields map[string]**string) bool {
+	changed := false
+	for name, field := range fields {
+		if d, ok := diff[name]; ok {
+			*field = String(d.b.(string))
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,48 @@
+package sqwiggle
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffAttachment(t *testing.T) {
+	old := Attachment{
+		ID:        1,
+		Type:      TypeFile,
+		Title:     "report.pdf",
+		URL:       "https://example.com/report.pdf",
+		Status:    AttachmentPending,
+		CreatedAt: time.Date(2015, time.February, 5, 4, 53, 5, 0, time.UTC),
+	}
+	if o := DiffAttachment(old, old); o != nil {
+		t.Errorf("DiffAttachment of equal attachments = %+v, want nil", o)
+	}
+
+	new := old
+	new.Status = AttachmentUploaded
+	new.Description = ""
+	new.Title = "Report"
+	new.UpdatedAt = time.Now() // not updatable, so ignored
+	o := DiffAttachment(old, new)
+	want := url.Values{"title": {"Report"}, "status": {"uploaded"}}
+	if got := o.values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffAttachment sets %v, want %v", got, want)
+	}
+}
+
+func TestDiffOrganization(t *testing.T) {
+	old := Organization{ID: 1, Name: "IronZebra", Path: "ironzebra"}
+	new := old
+	new.Path = "acme"
+	if o := DiffOrganization(old, new); o != nil {
+		t.Errorf("DiffOrganization with a new path = %+v, want nil", o)
+	}
+
+	new.Name = "Acme"
+	o := DiffOrganization(old, new)
+	if o == nil || o.Name == nil || *o.Name != "Acme" {
+		t.Errorf("DiffOrganization = %+v, want name %q", o, "Acme")
+	}
+}
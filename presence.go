@@ -0,0 +1,168 @@
+package sqwiggle
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// defaultPresenceInterval is how often a PresenceWatcher polls by default.
+const defaultPresenceInterval = 30 * time.Second
+
+// PresenceEventType is the kind of change described by a PresenceEvent.
+type PresenceEventType int
+
+// The below PresenceEventType constants define the changes to users that
+// a PresenceWatcher reports. A user that goes from offline to busy is
+// reported with a PresenceOnline and a PresenceBusy event.
+const (
+	PresenceJoined         PresenceEventType = iota // A user appeared in the organization
+	PresenceLeft                                    // A user is no longer listed
+	PresenceOnline                                  // A user went from offline to available or busy
+	PresenceOffline                                 // A user went offline
+	PresenceBusy                                    // A user became busy
+	PresenceAvailable                               // A user became available after being busy
+	PresenceMessageChanged                          // A user changed their status message
+	PresenceActive                                  // A user was active since the last poll
+	PresenceConnected                               // A user started a new online session
+)
+
+var presenceEventNames = []string{"joined", "left", "online", "offline", "busy", "available", "message changed", "active", "connected"}
+
+func (t PresenceEventType) String() string {
+	if t < 0 || int(t) >= len(presenceEventNames) {
+		return "unknown"
+	}
+	return presenceEventNames[t]
+}
+
+// PresenceEvent describes a change to a user between two polls of a
+// PresenceWatcher.
+type PresenceEvent struct {
+	Type     PresenceEventType
+	User     User      // The user as of this poll, or as last seen for PresenceLeft
+	Previous User      // The user as of the previous poll, zero for PresenceJoined
+	Time     time.Time // The time of the poll that detected the change
+}
+
+// PresenceWatcher polls the users of the organization and reports changes
+// to their status, status message and activity as events. The first poll
+// only records the current state of the users, and reports no events.
+// A PresenceWatcher must not be used concurrently.
+type PresenceWatcher struct {
+	API      UsersAPI
+	Interval time.Duration   // How often to poll, defaults to 30 seconds
+	OnError  func(err error) // Called, if set, when a poll fails; polling continues afterwards
+
+	users map[int]User
+}
+
+// NewPresenceWatcher returns a PresenceWatcher that polls api at the
+// default interval.
+func NewPresenceWatcher(api UsersAPI) *PresenceWatcher {
+	return &PresenceWatcher{API: api, Interval: defaultPresenceInterval}
+}
+
+// Watch polls until ctx is done, and sends the events of every poll on
+// the returned channel, which is closed when ctx is done.
+func (w *PresenceWatcher) Watch(ctx context.Context) <-chan PresenceEvent {
+	events := make(chan PresenceEvent)
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultPresenceInterval
+	}
+	go func() {
+		defer close(events)
+		for {
+			evs, err := w.Poll(ctx)
+			if err != nil && ctx.Err() == nil && w.OnError != nil {
+				w.OnError(err)
+			}
+			for _, ev := range evs {
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if sleep(ctx, interval) != nil {
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// Poll lists the users once, and returns the changes since the previous
+// poll, ordered by user ID. On error the previous state is kept.
+func (w *PresenceWatcher) Poll(ctx context.Context) ([]PresenceEvent, error) {
+	now := time.Now()
+	users := make(map[int]User)
+	for u, err := range w.API.AllUsers(ctx, nil) {
+		if err != nil {
+			return nil, err
+		}
+		users[u.ID] = u
+	}
+	previous := w.users
+	w.users = users
+	if previous == nil {
+		return nil, nil
+	}
+
+	var events []PresenceEvent
+	for id, u := range users {
+		if old, ok := previous[id]; ok {
+			events = append(events, presenceChanges(old, u, now)...)
+		} else {
+			events = append(events, PresenceEvent{Type: PresenceJoined, User: u, Time: now})
+		}
+	}
+	for id, old := range previous {
+		if _, ok := users[id]; !ok {
+			events = append(events, PresenceEvent{Type: PresenceLeft, User: old, Previous: old, Time: now})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].User.ID != events[j].User.ID {
+			return events[i].User.ID < events[j].User.ID
+		}
+		return events[i].Type < events[j].Type
+	})
+	return events, nil
+}
+
+// presenceChanges returns the events for the changes from old to u.
+func presenceChanges(old, u User, now time.Time) []PresenceEvent {
+	var types []PresenceEventType
+	if old.Status != u.Status {
+		switch {
+		case u.Status == StatusOffline:
+			types = append(types, PresenceOffline)
+		case old.Status == StatusOffline || old.Status == "":
+			types = append(types, PresenceOnline)
+			if u.Status == StatusBusy {
+				types = append(types, PresenceBusy)
+			}
+		case u.Status == StatusBusy:
+			types = append(types, PresenceBusy)
+		case u.Status == StatusAvailable:
+			types = append(types, PresenceAvailable)
+		}
+	}
+	if old.Message != u.Message {
+		types = append(types, PresenceMessageChanged)
+	}
+	if u.LastActiveAt.After(old.LastActiveAt) {
+		types = append(types, PresenceActive)
+	}
+	if !u.LastConnectedAt.Equal(old.LastConnectedAt) {
+		types = append(types, PresenceConnected)
+	}
+
+	events := make([]PresenceEvent, len(types))
+	for i, t := range types {
+		events[i] = PresenceEvent{Type: t, User: u, Previous: old, Time: now}
+	}
+	return events
+}
@@ -0,0 +1,140 @@
+package sqwiggle
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUsers is a UsersAPI that only implements AllUsers, returning the
+// current value of users.
+type fakeUsers struct {
+	UsersAPI
+
+	mu    sync.Mutex
+	users []User
+	err   error
+	polls int
+}
+
+func (f *fakeUsers) set(users []User, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users, f.err = users, err
+}
+
+func (f *fakeUsers) AllUsers(ctx context.Context, opts *PageOptions) iter.Seq2[User, error] {
+	f.mu.Lock()
+	users, err := f.users, f.err
+	f.polls++
+	f.mu.Unlock()
+	return func(yield func(User, error) bool) {
+		if err != nil {
+			yield(User{}, err)
+			return
+		}
+		for _, u := range users {
+			if !yield(u, nil) {
+				return
+			}
+		}
+	}
+}
+
+func presenceTypes(events []PresenceEvent) map[int][]PresenceEventType {
+	types := make(map[int][]PresenceEventType)
+	for _, ev := range events {
+		types[ev.User.ID] = append(types[ev.User.ID], ev.Type)
+	}
+	return types
+}
+
+func TestPresenceWatcher_Poll(t *testing.T) {
+	t0 := time.Date(2015, time.February, 5, 4, 53, 5, 0, time.UTC)
+	api := &fakeUsers{}
+	api.set([]User{
+		{ID: 1, Status: StatusOffline},
+		{ID: 2, Status: StatusAvailable, LastActiveAt: t0},
+		{ID: 3, Status: StatusBusy, Message: "Lunch"},
+		{ID: 4, Status: StatusOffline},
+		{ID: 5, Status: StatusAvailable, LastConnectedAt: t0},
+	}, nil)
+	w := NewPresenceWatcher(api)
+
+	events, err := w.Poll(context.Background())
+	if err != nil || len(events) != 0 {
+		t.Fatalf("first Poll = %v, %v, want no events", events, err)
+	}
+
+	api.set([]User{
+		{ID: 1, Status: StatusBusy, Message: "Busy"},
+		{ID: 2, Status: StatusAvailable, LastActiveAt: t0.Add(time.Minute)},
+		{ID: 3, Status: StatusAvailable},
+		{ID: 5, Status: StatusOffline, LastConnectedAt: t0.Add(time.Hour)},
+		{ID: 6, Status: StatusAvailable},
+	}, nil)
+	events, err = w.Poll(context.Background())
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	want := map[int][]PresenceEventType{
+		1: {PresenceOnline, PresenceBusy, PresenceMessageChanged},
+		2: {PresenceActive},
+		3: {PresenceAvailable, PresenceMessageChanged},
+		4: {PresenceLeft},
+		5: {PresenceOffline, PresenceConnected},
+		6: {PresenceJoined},
+	}
+	if got := presenceTypes(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("Poll events = %v, want %v", got, want)
+	}
+	if events[0].Previous.Status != StatusOffline {
+		t.Errorf("Previous.Status = %q, want %q", events[0].Previous.Status, StatusOffline)
+	}
+
+	// a failed poll keeps the previous state
+	api.set(nil, errors.New("boom"))
+	if _, err := w.Poll(context.Background()); err == nil {
+		t.Error("Poll did not return the error of AllUsers")
+	}
+	api.set([]User{{ID: 1, Status: StatusBusy, Message: "Busy"}}, nil)
+	events, _ = w.Poll(context.Background())
+	if got := presenceTypes(events); len(got) != 4 || len(got[1]) != 0 {
+		t.Errorf("Poll events = %v, want users 2, 3, 5 and 6 to have left", got)
+	}
+}
+
+func TestPresenceWatcher_Watch(t *testing.T) {
+	api := &fakeUsers{}
+	api.set([]User{{ID: 1, Status: StatusOffline}}, nil)
+	w := NewPresenceWatcher(api)
+	w.Interval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := w.Watch(ctx)
+
+	// wait for the first poll to record the initial state
+	for {
+		api.mu.Lock()
+		polls := api.polls
+		api.mu.Unlock()
+		if polls > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	api.set([]User{{ID: 1, Status: StatusAvailable}}, nil)
+	ev := <-events
+	if ev.Type != PresenceOnline || ev.User.ID != 1 {
+		t.Errorf("got event %v for user %d, want %v for user 1", ev.Type, ev.User.ID, PresenceOnline)
+	}
+
+	cancel()
+	for range events {
+	}
+}
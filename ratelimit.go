@@ -0,0 +1,182 @@
+package sqwiggle
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultRateLimitWait is how long the client waits after being rate
+// limited when the response does not say when the limit resets.
+const defaultRateLimitWait = time.Second
+
+// defaultMaxRateLimitWaits is how many times a request waits out a rate
+// limit by default, see Client.MaxRateLimitWaits.
+const defaultMaxRateLimitWaits = 3
+
+// RateLimit describes the rate limiting state reported by the API in the
+// headers of a response. Fields are left at their zero value when the
+// corresponding header was not present.
+type RateLimit struct {
+	Limit      int           // Maximum number of requests allowed in the current window (X-RateLimit-Limit)
+	Remaining  int           // Number of requests left in the current window (X-RateLimit-Remaining)
+	Reset      time.Time     // The time at which the current window resets (X-RateLimit-Reset)
+	RetryAfter time.Duration // How long to wait before making another request (Retry-After)
+}
+
+// wait returns how long a client should wait before trying again, given
+// that it was rate limited at time now.
+func (rl RateLimit) wait(now time.Time) time.Duration {
+	if rl.RetryAfter > 0 {
+		return rl.RetryAfter
+	}
+	if !rl.Reset.IsZero() && rl.Reset.After(now) {
+		return rl.Reset.Sub(now)
+	}
+	return defaultRateLimitWait
+}
+
+// parseRateLimit extracts the rate limiting headers from a response
+// received at time now. Retry-After may be given either in seconds or as
+// an HTTP date; X-RateLimit-Reset may be a unix timestamp or a number of
+// seconds from now.
+func parseRateLimit(h http.Header, now time.Time) RateLimit {
+	var rl RateLimit
+	if v, err := strconv.Atoi(h.Get("X-RateLimit-Limit")); err == nil {
+		rl.Limit = v
+	}
+	if v, err := strconv.Atoi(h.Get("X-RateLimit-Remaining")); err == nil {
+		rl.Remaining = v
+	}
+	if v, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if v < 1e9 {
+			// too small to be a timestamp, so it must be relative
+			rl.Reset = now.Add(time.Duration(v) * time.Second)
+		} else {
+			rl.Reset = time.Unix(v, 0)
+		}
+	}
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			rl.RetryAfter = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(v); err == nil && t.After(now) {
+			rl.RetryAfter = t.Sub(now)
+		}
+	}
+	return rl
+}
+
+// rateLimited reports whether a response indicates that the client has
+// been rate limited.
+func rateLimited(statusCode int, body []byte) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if statusCode < 400 {
+		return false
+	}
+	var apiErr Error
+	return json.Unmarshal(body, &apiErr) == nil && apiErr.Type == ErrLimitReached
+}
+
+// ResponseMeta holds metadata about the HTTP response to an API call,
+// such as the rate limiting headers. Use WithResponseMeta to have it
+// filled in.
+type ResponseMeta struct {
+	StatusCode int
+	Header     http.Header
+	RateLimit  RateLimit
+}
+
+type responseMetaKey struct{}
+
+// WithResponseMeta returns a copy of ctx that makes the Client record
+// metadata about the response to any ...Context call made with it into
+// meta. If the request was retried, meta describes the last response.
+func WithResponseMeta(ctx context.Context, meta *ResponseMeta) context.Context {
+	return context.WithValue(ctx, responseMetaKey{}, meta)
+}
+
+func responseMetaFromContext(ctx context.Context) *ResponseMeta {
+	meta, _ := ctx.Value(responseMetaKey{}).(*ResponseMeta)
+	return meta
+}
+
+// Limiter is a token bucket rate limiter that throttles requests on the
+// client side. It is safe for concurrent use, so a single Limiter can be
+// shared by all goroutines using a Client. When the API reports that the
+// rate limit was reached, the Limiter holds back all requests until the
+// limit resets.
+type Limiter struct {
+	mu           sync.Mutex
+	rate         float64 // tokens added per second
+	burst        float64 // maximum number of tokens in the bucket
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// NewLimiter returns a Limiter that allows requestsPerSecond requests per
+// second on average, with bursts of up to burst requests. If
+// requestsPerSecond is not positive, requests are not throttled, but are
+// still held back while the API reports the rate limit as reached.
+func NewLimiter(requestsPerSecond float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request may be made, or until ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	for {
+		d := l.reserve(time.Now())
+		if d == 0 {
+			return nil
+		}
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token from the bucket and returns zero, or returns how
+// long to wait before trying again if no token is available.
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.blockedUntil) {
+		return l.blockedUntil.Sub(now)
+	}
+	if l.rate <= 0 {
+		return 0
+	}
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// blockFor holds back all requests for the duration d.
+func (l *Limiter) blockFor(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
@@ -0,0 +1,183 @@
+package sqwiggle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2015, time.February, 7, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   RateLimit
+	}{
+		{
+			name:   "no headers",
+			header: http.Header{},
+			want:   RateLimit{},
+		},
+		{
+			name: "all headers",
+			header: http.Header{
+				"X-Ratelimit-Limit":     {"100"},
+				"X-Ratelimit-Remaining": {"0"},
+				"X-Ratelimit-Reset":     {"1423296060"},
+				"Retry-After":           {"30"},
+			},
+			want: RateLimit{
+				Limit:      100,
+				Remaining:  0,
+				Reset:      time.Unix(1423296060, 0),
+				RetryAfter: 30 * time.Second,
+			},
+		},
+		{
+			name: "relative reset and HTTP date",
+			header: http.Header{
+				"X-Ratelimit-Reset": {"60"},
+				"Retry-After":       {"Sat, 07 Feb 2015 08:00:10 GMT"},
+			},
+			want: RateLimit{
+				Reset:      now.Add(time.Minute),
+				RetryAfter: 10 * time.Second,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := parseRateLimit(tt.header, now)
+		if got.Limit != tt.want.Limit || got.Remaining != tt.want.Remaining ||
+			!got.Reset.Equal(tt.want.Reset) || got.RetryAfter != tt.want.RetryAfter {
+			t.Errorf("%q case: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Test_RateLimit_Meta checks that rate limiting headers are recorded in
+// the ResponseMeta attached to the context.
+func Test_RateLimit_Meta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "59")
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	client := NewClient("test")
+	client.RootURL = server.URL
+
+	var meta ResponseMeta
+	_, err := client.ListUsersContext(WithResponseMeta(context.Background(), &meta), 0, 0)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if meta.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want %d", meta.StatusCode, http.StatusOK)
+	}
+	if meta.RateLimit.Limit != 60 || meta.RateLimit.Remaining != 59 {
+		t.Errorf("RateLimit = %+v, want Limit 60 and Remaining 59", meta.RateLimit)
+	}
+}
+
+// Test_RateLimit_Wait checks that the client waits out ErrLimitReached
+// responses when WaitOnRateLimit is set.
+func Test_RateLimit_Wait(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"type": "limit_reached", "message": "Slow down"}`))
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	client := NewClient("test")
+	client.RootURL = server.URL
+
+	// without waiting, the error is returned as is
+	_, err := client.ListStreams(0, 0)
+	if !errors.Is(err, ErrLimitReached) {
+		t.Fatalf("err = %v, want error of type %q", err, ErrLimitReached)
+	}
+
+	calls = 0
+	client.WaitOnRateLimit = true
+	_, err = client.ListStreams(0, 0)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want %d", calls, 2)
+	}
+}
+
+// Test_RateLimit_WaitCap checks that the client gives up waiting when the
+// API keeps responding with 429 Too Many Requests.
+func Test_RateLimit_WaitCap(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"type": "limit_reached", "message": "Slow down"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test")
+	client.RootURL = server.URL
+	client.WaitOnRateLimit = true
+	client.MaxRateLimitWaits = 1
+
+	_, err := client.ListStreams(0, 0)
+	var apiErr *APIError
+	if !errors.Is(err, ErrLimitReached) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want a 429 error of type %q", err, ErrLimitReached)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want %d", calls, 2)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(10, 2)
+	now := l.last
+
+	// the burst is available immediately
+	for i := 0; i < 2; i++ {
+		if d := l.reserve(now); d != 0 {
+			t.Fatalf("reserve #%d = %v, want 0", i, d)
+		}
+	}
+	// then tokens arrive every 100ms
+	if d := l.reserve(now); d != 100*time.Millisecond {
+		t.Errorf("reserve on empty bucket = %v, want %v", d, 100*time.Millisecond)
+	}
+	if d := l.reserve(now.Add(100 * time.Millisecond)); d != 0 {
+		t.Errorf("reserve after refill = %v, want 0", d)
+	}
+
+	// blocking holds back requests regardless of available tokens
+	l.blockFor(time.Hour)
+	if d := l.reserve(time.Now().Add(time.Hour)); d != 0 {
+		t.Errorf("reserve after block expired = %v, want 0", d)
+	}
+	if d := l.reserve(time.Now()); d < 59*time.Minute {
+		t.Errorf("reserve while blocked = %v, want about an hour", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait with cancelled context = %v, want %v", err, context.Canceled)
+	}
+}
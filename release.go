@@ -0,0 +1,173 @@
+package sqwiggle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Platforms of the Sqwiggle apps, as used in the releases of Info.
+const (
+	PlatformWindows = "windows"
+	PlatformIOS     = "ios"
+	PlatformMac     = "mac"
+)
+
+// Version is a semantic version, such as "0.6.5" or "1.2.0-beta.1".
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          string // Optional pre-release identifiers, such as "beta.1"
+	Build               string // Optional build metadata, which is ignored when comparing
+}
+
+// ParseVersion parses a semantic version. A leading "v" is allowed, and
+// the minor and patch versions may be left out, in which case they are 0.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	rest := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		rest, v.Build = rest[:i], rest[i+1:]
+	}
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		rest, v.Prerelease = rest[:i], rest[i+1:]
+		if v.Prerelease == "" {
+			return Version{}, fmt.Errorf("sqwiggle: invalid version %q: empty pre-release", s)
+		}
+	}
+	parts := strings.Split(rest, ".")
+	if len(parts) > 3 {
+		return Version{}, fmt.Errorf("sqwiggle: invalid version %q", s)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("sqwiggle: invalid version %q", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// String returns the version in its canonical form, without a leading "v".
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1 if v is older than w, 1 if it is newer, and 0 if
+// they are the same version. Precedence follows semantic versioning: a
+// pre-release is older than the release it precedes.
+func (v Version) Compare(w Version) int {
+	if c := compareInt(v.Major, w.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, w.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, w.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case v.Prerelease == w.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case w.Prerelease == "":
+		return -1
+	}
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(w.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(a), len(b))
+}
+
+// compareIdentifier compares pre-release identifiers: numeric identifiers
+// are compared as numbers, and are older than alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	m, errA := strconv.Atoi(a)
+	n, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInt(m, n)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Upgrade says whether an installed app should be upgraded.
+type Upgrade int
+
+const (
+	UpgradeNone        Upgrade = iota // The installed version is current
+	UpgradeRecommended                // A newer version is available, but the installed version is still supported
+	UpgradeRequired                   // The installed version is older than the minimum supported version
+)
+
+func (u Upgrade) String() string {
+	switch u {
+	case UpgradeNone:
+		return "none"
+	case UpgradeRecommended:
+		return "recommended"
+	case UpgradeRequired:
+		return "required"
+	}
+	return "Upgrade(" + strconv.Itoa(int(u)) + ")"
+}
+
+// Check reports whether the installed version of the app should be
+// upgraded to this release.
+func (r Release) Check(installed string) (Upgrade, error) {
+	v, err := ParseVersion(installed)
+	if err != nil {
+		return UpgradeNone, err
+	}
+	minimum, err := ParseVersion(r.Minimum)
+	if err != nil {
+		return UpgradeNone, err
+	}
+	current, err := ParseVersion(r.Current)
+	if err != nil {
+		return UpgradeNone, err
+	}
+	switch {
+	case v.Compare(minimum) < 0:
+		return UpgradeRequired, nil
+	case v.Compare(current) < 0:
+		return UpgradeRecommended, nil
+	}
+	return UpgradeNone, nil
+}
+
+// CheckRelease reports whether the installed version of the app for the
+// given platform, such as PlatformMac, should be upgraded.
+func (i Info) CheckRelease(platform, installed string) (Upgrade, error) {
+	r, ok := i.Releases[platform]
+	if !ok {
+		return UpgradeNone, fmt.Errorf("sqwiggle: no release for platform %q", platform)
+	}
+	return r.Check(installed)
+}
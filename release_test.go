@@ -0,0 +1,82 @@
+package sqwiggle
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Version
+	}{
+		{"0.6.5", Version{Major: 0, Minor: 6, Patch: 5}},
+		{"v1.2", Version{Major: 1, Minor: 2}},
+		{"2.0.0-beta.1+build.7", Version{Major: 2, Prerelease: "beta.1", Build: "build.7"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.in)
+		if err != nil {
+			t.Errorf("ParseVersion(%q) returned error: %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseVersion(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"", "1.2.3.4", "a.b.c", "1.2.3-", "1.-2.0"} {
+		if _, err := ParseVersion(in); err == nil {
+			t.Errorf("ParseVersion(%q) did not return an error", in)
+		}
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	// in ascending order of precedence
+	ordered := []string{"0.9.9", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.10.0"}
+	for i := range ordered {
+		for j := range ordered {
+			v, _ := ParseVersion(ordered[i])
+			w, _ := ParseVersion(ordered[j])
+			if got, want := v.Compare(w), compareInt(i, j); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", v, w, got, want)
+			}
+		}
+	}
+}
+
+func TestInfo_CheckRelease(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/info.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info Info
+	if err := json.Unmarshal(b, &info); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		platform, installed string
+		want                Upgrade
+	}{
+		{PlatformIOS, "0.0.9", UpgradeRequired},
+		{PlatformIOS, "0.1.0", UpgradeRecommended},
+		{PlatformIOS, "0.1.2", UpgradeNone},
+		{PlatformIOS, "0.2.0-beta", UpgradeNone},
+		{PlatformMac, "0.6.4", UpgradeRequired},
+		{PlatformMac, "v0.6.5", UpgradeNone},
+	}
+	for _, tt := range tests {
+		got, err := info.CheckRelease(tt.platform, tt.installed)
+		if err != nil {
+			t.Errorf("CheckRelease(%q, %q) returned error: %v", tt.platform, tt.installed, err)
+		}
+		if got != tt.want {
+			t.Errorf("CheckRelease(%q, %q) = %v, want %v", tt.platform, tt.installed, got, tt.want)
+		}
+	}
+
+	if _, err := info.CheckRelease("linux", "1.0.0"); err == nil {
+		t.Error("CheckRelease of an unknown platform did not return an error")
+	}
+}
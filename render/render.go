@@ -0,0 +1,259 @@
+// Package render renders Sqwiggle messages as Markdown, HTML or plain
+// text for terminals. Mentions are rendered as links to the mentioned
+// users, and the attachments of a message are summarized after its text
+// according to their type.
+//
+//	opts := &render.Options{UserURL: func(m sqwiggle.Mention) string {
+//		return fmt.Sprintf("https://chat.example.com/users/%d", m.SubjectID)
+//	}}
+//	fmt.Println(render.Markdown(msg, opts))
+package render
+
+import (
+	"html"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/hermanschaaf/sqwiggle"
+)
+
+// Options controls how messages are rendered. A nil *Options is
+// equivalent to the zero value.
+type Options struct {
+	// UserURL returns the URL that a mention of a user links to. It is
+	// only called for mentions whose SubjectType is sqwiggle.TypeUser;
+	// other mentions are never linked. If it is nil, the default, or
+	// returns an empty string, the mention is rendered without a link.
+	UserURL func(m sqwiggle.Mention) string
+
+	// NoColor turns off the ANSI colors of Text.
+	NoColor bool
+}
+
+// userURL returns the URL to link the mention to, or "" if there is none.
+func (o *Options) userURL(m sqwiggle.Mention) string {
+	if o == nil || o.UserURL == nil || m.SubjectType != sqwiggle.TypeUser {
+		return ""
+	}
+	return o.UserURL(m)
+}
+
+// attachmentLabels are the labels of the known attachment types in
+// attachment summaries.
+var attachmentLabels = map[sqwiggle.AttachmentType]string{
+	sqwiggle.TypeImage:         "Image",
+	sqwiggle.TypeLink:          "Link",
+	sqwiggle.TypeFile:          "File",
+	sqwiggle.TypeTwitterStatus: "Tweet",
+	sqwiggle.TypeTwitterUser:   "Twitter user",
+	sqwiggle.TypeVideo:         "Video",
+	sqwiggle.TypeCode:          "Code",
+	sqwiggle.TypeGist:          "Gist",
+}
+
+// label returns the label of the type of a, such as "Image".
+func label(a sqwiggle.Attachment) string {
+	if l, ok := attachmentLabels[a.Type]; ok {
+		return l
+	}
+	return "Attachment"
+}
+
+// title returns the title of a, falling back to its URL.
+func title(a sqwiggle.Attachment) string {
+	if a.Title != "" {
+		return a.Title
+	}
+	return a.URL
+}
+
+// note returns the extra information shown after the title of a: its
+// description, or that it is still being uploaded.
+func note(a sqwiggle.Attachment) string {
+	if a.Status == sqwiggle.AttachmentPending {
+		return "uploading"
+	}
+	return a.Description
+}
+
+// safeURL returns u if it is an absolute http, https or mailto URL, and
+// "" otherwise, so that rendered links cannot run scripts.
+func safeURL(u string) string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	switch strings.ToLower(p.Scheme) {
+	case "http", "https", "mailto":
+		return p.String()
+	}
+	return ""
+}
+
+/*************************************************************************
+
+  Markdown
+
+*************************************************************************/
+
+// markdownEscaper escapes the characters that have a meaning in Markdown.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`,
+)
+
+// markdownURL escapes the characters of u that would end a Markdown link.
+func markdownURL(u string) string {
+	return strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(u)
+}
+
+// markdownLink returns a link to u with the given text, or only the text
+// if u is not safe.
+func markdownLink(text, u string) string {
+	if u = safeURL(u); u == "" {
+		return markdownEscaper.Replace(text)
+	}
+	return "[" + markdownEscaper.Replace(text) + "](" + markdownURL(u) + ")"
+}
+
+// Markdown renders m as Markdown. Mentions become links, and the
+// attachments are listed after the text, images inline.
+func Markdown(m sqwiggle.Message, opts *Options) string {
+	var b strings.Builder
+	for _, seg := range m.Segments() {
+		if seg.Mention == nil {
+			b.WriteString(markdownEscaper.Replace(seg.Text))
+			continue
+		}
+		b.WriteString("**" + markdownLink("@"+seg.Text, opts.userURL(*seg.Mention)) + "**")
+	}
+	for i, a := range m.Attachments {
+		if i == 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n- " + label(a) + ": ")
+		if u := safeURL(a.URL); u != "" && a.Type == sqwiggle.TypeImage {
+			b.WriteString("![" + markdownEscaper.Replace(title(a)) + "](" + markdownURL(u) + ")")
+		} else {
+			b.WriteString(markdownLink(title(a), a.URL))
+		}
+		if n := note(a); n != "" {
+			b.WriteString(" - " + markdownEscaper.Replace(n))
+		}
+	}
+	return b.String()
+}
+
+/*************************************************************************
+
+  HTML
+
+*************************************************************************/
+
+// HTML renders m as HTML that is safe to include in a page: all text is
+// escaped, and only http, https and mailto URLs are linked. The text is
+// wrapped in a <p>, with line breaks kept, and the attachments are listed
+// in a <ul class="attachments">.
+func HTML(m sqwiggle.Message, opts *Options) string {
+	var b strings.Builder
+	b.WriteString(`<p class="message">`)
+	for _, seg := range m.Segments() {
+		if seg.Mention == nil {
+			b.WriteString(strings.ReplaceAll(html.EscapeString(seg.Text), "\n", "<br>"))
+			continue
+		}
+		name := html.EscapeString("@" + seg.Text)
+		if u := safeURL(opts.userURL(*seg.Mention)); u != "" {
+			b.WriteString(`<a class="mention" href="` + html.EscapeString(u) + `">` + name + `</a>`)
+		} else {
+			b.WriteString(`<span class="mention">` + name + `</span>`)
+		}
+	}
+	b.WriteString(`</p>`)
+
+	if len(m.Attachments) == 0 {
+		return b.String()
+	}
+	b.WriteString(`<ul class="attachments">`)
+	for _, a := range m.Attachments {
+		b.WriteString(`<li class="attachment attachment-` + html.EscapeString(string(a.Type)) + `">`)
+		content := html.EscapeString(title(a))
+		img := safeURL(a.Image)
+		if u := safeURL(a.URL); u != "" && a.Type == sqwiggle.TypeImage {
+			img = u
+		}
+		if img != "" {
+			content = `<img src="` + html.EscapeString(img) + `" alt="` + html.EscapeString(title(a)) + `">`
+		}
+		if u := safeURL(a.URL); u != "" {
+			content = `<a href="` + html.EscapeString(u) + `" rel="nofollow noopener">` + content + `</a>`
+		}
+		b.WriteString(html.EscapeString(label(a)) + ": " + content)
+		if n := note(a); n != "" {
+			b.WriteString(` <span class="note">` + html.EscapeString(n) + `</span>`)
+		}
+		b.WriteString(`</li>`)
+	}
+	b.WriteString(`</ul>`)
+	return b.String()
+}
+
+/*************************************************************************
+
+  Text
+
+*************************************************************************/
+
+// ANSI escape codes used by Text.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiMention = "\x1b[1;36m" // bold cyan
+	ansiLabel   = "\x1b[33m"   // yellow
+	ansiURL     = "\x1b[4m"    // underlined
+	ansiNote    = "\x1b[2m"    // faint
+)
+
+// sanitize replaces control characters other than newlines and tabs, so
+// that text cannot send escape sequences to the terminal.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return unicode.ReplacementChar
+		}
+		return r
+	}, s)
+}
+
+// Text renders m as plain text for a terminal, with mentions and the
+// attachment summaries colored with ANSI escape codes unless
+// opts.NoColor is set. Mentions are followed by the URL they link to.
+func Text(m sqwiggle.Message, opts *Options) string {
+	color := func(code, s string) string {
+		if opts != nil && opts.NoColor {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	var b strings.Builder
+	for _, seg := range m.Segments() {
+		if seg.Mention == nil {
+			b.WriteString(sanitize(seg.Text))
+			continue
+		}
+		b.WriteString(color(ansiMention, "@"+sanitize(seg.Text)))
+		if u := safeURL(opts.userURL(*seg.Mention)); u != "" {
+			b.WriteString(" <" + sanitize(u) + ">")
+		}
+	}
+	for _, a := range m.Attachments {
+		b.WriteString("\n  " + color(ansiLabel, "["+label(a)+"]") + " " + sanitize(title(a)))
+		if u := safeURL(a.URL); u != "" && u != title(a) {
+			b.WriteString(" " + color(ansiURL, sanitize(u)))
+		}
+		if n := note(a); n != "" {
+			b.WriteString(" " + color(ansiNote, "("+sanitize(n)+")"))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package render
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hermanschaaf/sqwiggle"
+)
+
+// message is rendered from "Hi @(trin)[user:4], see <this> *now*\nthanks".
+var message = sqwiggle.Message{
+	Text: "Hi trin, see <this> *now*\nthanks",
+	Mentions: []sqwiggle.Mention{
+		{Name: "trin", Indices: []int{3, 7}, SubjectType: sqwiggle.TypeUser, SubjectID: 4},
+	},
+	Attachments: []sqwiggle.Attachment{
+		{Type: sqwiggle.TypeImage, Title: "cat.gif", URL: "https://example.com/cat.gif"},
+		{Type: sqwiggle.TypeLink, Title: "Example", URL: "https://example.com/", Description: "An example"},
+		{Type: sqwiggle.TypeFile, Title: "report.pdf", URL: "javascript:alert(1)", Status: sqwiggle.AttachmentPending},
+	},
+}
+
+// trin mentions the user at the start of a message.
+var trin = []sqwiggle.Mention{{Name: "trin", Indices: []int{0, 4}, SubjectType: sqwiggle.TypeUser, SubjectID: 4}}
+
+// helpdesk mentions a client, rather than a user, at the start of a
+// message.
+var helpdesk = []sqwiggle.Mention{{Name: "helpdesk", Indices: []int{0, 8}, SubjectType: sqwiggle.TypeClient, SubjectID: 4}}
+
+var opts = &Options{
+	UserURL: func(m sqwiggle.Mention) string {
+		return fmt.Sprintf("https://chat.example.com/users/%d", m.SubjectID)
+	},
+}
+
+func TestMarkdown(t *testing.T) {
+	want := "Hi **[@trin](https://chat.example.com/users/4)**, see \\<this\\> \\*now\\*\nthanks\n" +
+		"\n- Image: ![cat.gif](https://example.com/cat.gif)" +
+		"\n- Link: [Example](https://example.com/) - An example" +
+		"\n- File: report.pdf - uploading"
+	if got := Markdown(message, opts); got != want {
+		t.Errorf("Markdown = %q, want %q", got, want)
+	}
+
+	// without a URL for users, mentions are not linked
+	if got, want := Markdown(sqwiggle.Message{Text: "trin", Mentions: trin}, nil), "**@trin**"; got != want {
+		t.Errorf("Markdown = %q, want %q", got, want)
+	}
+
+	// mentions of anything but users are not linked either
+	if got, want := Markdown(sqwiggle.Message{Text: "helpdesk", Mentions: helpdesk}, opts), "**@helpdesk**"; got != want {
+		t.Errorf("Markdown = %q, want %q", got, want)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	want := `<p class="message">Hi <a class="mention" href="https://chat.example.com/users/4">@trin</a>, see &lt;this&gt; *now*<br>thanks</p>` +
+		`<ul class="attachments">` +
+		`<li class="attachment attachment-image">Image: <a href="https://example.com/cat.gif" rel="nofollow noopener"><img src="https://example.com/cat.gif" alt="cat.gif"></a></li>` +
+		`<li class="attachment attachment-link">Link: <a href="https://example.com/" rel="nofollow noopener">Example</a> <span class="note">An example</span></li>` +
+		`<li class="attachment attachment-file">File: report.pdf <span class="note">uploading</span></li>` +
+		`</ul>`
+	if got := HTML(message, opts); got != want {
+		t.Errorf("HTML =\n%s\nwant\n%s", got, want)
+	}
+
+	unsafe := &Options{UserURL: func(sqwiggle.Mention) string { return `javascript:alert("hi")` }}
+	if got, want := HTML(sqwiggle.Message{Text: "trin", Mentions: trin}, unsafe), `<p class="message"><span class="mention">@trin</span></p>`; got != want {
+		t.Errorf("HTML = %q, want %q", got, want)
+	}
+	if got, want := HTML(sqwiggle.Message{Text: "helpdesk", Mentions: helpdesk}, opts), `<p class="message"><span class="mention">@helpdesk</span></p>`; got != want {
+		t.Errorf("HTML = %q, want %q", got, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	want := "Hi @trin <https://chat.example.com/users/4>, see <this> *now*\nthanks" +
+		"\n  [Image] cat.gif https://example.com/cat.gif" +
+		"\n  [Link] Example https://example.com/ (An example)" +
+		"\n  [File] report.pdf (uploading)"
+	if got := Text(message, &Options{UserURL: opts.UserURL, NoColor: true}); got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+
+	m := sqwiggle.Message{Text: "trin \x1b[2Jcleared", Mentions: trin}
+	if got, want := Text(m, nil), "\x1b[1;36m@trin\x1b[0m �[2Jcleared"; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package sqwiggle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy describes how the Client retries requests that failed
+// because of a network error or a transient error response. Set it on
+// Client.Retry to enable retries; DefaultRetryPolicy returns a policy
+// that is suitable for most uses.
+//
+// GET, PUT and DELETE requests are idempotent in the Sqwiggle API and are
+// always safe to retry. POST requests create new resources (messages,
+// streams, invites, ...), so retrying them could create duplicates; they
+// are only retried if RetryPOST is set.
+type RetryPolicy struct {
+	MaxAttempts int           // Total number of attempts, including the first one
+	BaseDelay   time.Duration // Delay before the first retry, doubled for every subsequent retry
+	MaxDelay    time.Duration // Upper bound on the delay between two attempts
+	Jitter      float64       // Fraction of each delay, between 0 and 1, that is randomized
+
+	StatusCodes []int       // HTTP status codes of responses that should be retried
+	ErrorTypes  []ErrorType // Types of API errors that should be retried, regardless of status code
+
+	RetryPOST bool // Whether POST requests may be retried
+}
+
+// DefaultRetryPolicy returns a RetryPolicy that makes up to three attempts,
+// backing off exponentially from 500ms up to 10s with 20% jitter, and
+// retries responses with status 500, 502, 503 or 504.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Jitter:      0.2,
+		StatusCodes: []int{
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// RetryError is returned when a request was retried but none of the
+// attempts succeeded. Err is the error of the last attempt.
+type RetryError struct {
+	Attempts int   // Number of attempts that were made
+	Err      error // Error returned by the last attempt
+}
+
+// Error is an implementation of the error interface
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("sqwiggle: giving up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt, so that errors.Is and
+// errors.As see through a RetryError.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+type attemptKey struct{}
+
+// withAttempt returns a copy of ctx recording the (1-based) attempt number.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// Attempt returns the attempt number of the request whose context is
+// ctx: 1 for the first attempt, 2 for the first retry, and so on. It is
+// meant to be used by Middleware, as Attempt(req.Context()), and returns 1
+// for contexts that do not belong to a request made by a Client.
+func Attempt(ctx context.Context) int {
+	if n, ok := ctx.Value(attemptKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
+
+// maxAttempts returns the total number of attempts allowed by the policy.
+// A nil policy allows a single attempt.
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// retryable reports whether an attempt with the given outcome should be
+// retried under this policy.
+func (p *RetryPolicy) retryable(method string, resp *response, err error) bool {
+	if p == nil {
+		return false
+	}
+	if method == "POST" && !p.RetryPOST {
+		return false
+	}
+	if err != nil {
+		// network errors are always worth another try, unless the
+		// caller gave up on the request
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	if resp.statusCode < 400 {
+		return false
+	}
+	for _, code := range p.StatusCodes {
+		if code == resp.statusCode {
+			return true
+		}
+	}
+	if len(p.ErrorTypes) > 0 {
+		var apiErr Error
+		if json.Unmarshal(resp.body, &apiErr) == nil {
+			for _, t := range p.ErrorTypes {
+				if t == apiErr.Type {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// backoff returns how long to wait after the given (1-based) attempt
+// before making the next one.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		j := p.Jitter
+		if j > 1 {
+			j = 1
+		}
+		spread := float64(d) * j
+		d = time.Duration(float64(d) - spread + rand.Float64()*spread)
+	}
+	return d
+}
+
+// sleep waits for d, or until ctx is done, whichever happens first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,217 @@
+package sqwiggle
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testRetryPolicy returns a policy that retries quickly, for use in tests.
+func testRetryPolicy() *RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.BaseDelay = time.Millisecond
+	p.MaxDelay = 5 * time.Millisecond
+	return p
+}
+
+// Test_Retry_Success checks that a PUT request is retried after 503
+// responses and that the eventual successful response is returned.
+func Test_Retry_Success(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/postmessage.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// set up server that fails the first two attempts
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(dummy)
+	}))
+	defer server.Close()
+
+	client := NewClient("test")
+	client.RootURL = server.URL
+	client.Retry = testRetryPolicy()
+
+	// record the attempt numbers seen by middleware
+	var attempts []int
+	client.Middleware = []Middleware{
+		func(next Handler) Handler {
+			return func(op string, req *http.Request) (*http.Response, error) {
+				attempts = append(attempts, Attempt(req.Context()))
+				return next(op, req)
+			}
+		},
+	}
+
+	m, err := client.UpdateMessage(1, "wow")
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	validateMessage(t, m)
+	if calls != 3 {
+		t.Errorf("calls = %d, want %d", calls, 3)
+	}
+	if fmt.Sprint(attempts) != "[1 2 3]" {
+		t.Errorf("attempts = %v, want [1 2 3]", attempts)
+	}
+}
+
+// Test_Retry_Exhausted checks that the number of attempts is reported
+// once the retry policy gives up, and that the API error is preserved.
+func Test_Retry_Exhausted(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/error.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	server, client := setupTestServer(502, dummy, func(r *http.Request) {
+		calls++
+	})
+	defer server.Close()
+	client.Retry = testRetryPolicy()
+
+	_, err = client.ListStreams(0, 0)
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("err = %v, want *RetryError", err)
+	}
+	if retryErr.Attempts != 3 {
+		t.Errorf("Attempts = %d, want %d", retryErr.Attempts, 3)
+	}
+	var apiErr Error
+	if !errors.As(err, &apiErr) || apiErr.Type != ErrAuthentication {
+		t.Errorf("err = %v, want wrapped Error of type %q", err, ErrAuthentication)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want %d", calls, 3)
+	}
+}
+
+// Test_Retry_FinalError checks that the attempts are reported when a
+// retried request ends in an error that is not retryable itself.
+func Test_Retry_FinalError(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"type": "unknown", "message": "Not found"}`))
+	}))
+	defer server.Close()
+	client := NewClient("test")
+	client.RootURL = server.URL
+	client.Retry = testRetryPolicy()
+
+	_, err := client.GetStream(1)
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 2 {
+		t.Fatalf("err = %v, want *RetryError after %d attempts", err, 2)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("err = %v, want wrapped 404 APIError", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want %d", calls, 2)
+	}
+}
+
+// Test_Retry_POST checks that POST requests are only retried when the
+// policy explicitly allows it.
+func Test_Retry_POST(t *testing.T) {
+	for _, retryPOST := range []bool{false, true} {
+		calls := 0
+		server, client := setupTestServer(503, []byte("{}"), func(r *http.Request) {
+			calls++
+		})
+		client.Retry = testRetryPolicy()
+		client.Retry.RetryPOST = retryPOST
+
+		_, err := client.PostStream("test")
+		if err == nil {
+			t.Errorf("RetryPOST = %t: got nil error", retryPOST)
+		}
+
+		want := 1
+		if retryPOST {
+			want = 3
+		}
+		if calls != want {
+			t.Errorf("RetryPOST = %t: calls = %d, want %d", retryPOST, calls, want)
+		}
+		server.Close()
+	}
+}
+
+// Test_Retry_ErrorTypes checks that responses are retried based on the
+// type of the API error they contain.
+func Test_Retry_ErrorTypes(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/error.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	server, client := setupTestServer(401, dummy, func(r *http.Request) {
+		calls++
+	})
+	defer server.Close()
+	client.Retry = testRetryPolicy()
+	client.Retry.MaxAttempts = 2
+	client.Retry.ErrorTypes = []ErrorType{ErrAuthentication}
+
+	err = client.DeleteStream(1)
+	if err == nil {
+		t.Fatal("got nil error")
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want %d", calls, 2)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  time.Second,
+	}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	// with jitter, the delay must stay within the jitter window
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := p.backoff(2)
+		if got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("backoff(2) with jitter = %v, want between 100ms and 200ms", got)
+		}
+	}
+}
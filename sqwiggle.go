@@ -2,13 +2,16 @@
 package sqwiggle
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	_ "crypto/sha512" // for verifying signature from COMODO RSA Certification Authority
 )
@@ -25,6 +28,43 @@ type Client struct {
 	APIKey     string
 	RootURL    string
 	HTTPClient *http.Client
+
+	// Retry controls whether and how failed requests are retried.
+	// If nil, every request is attempted exactly once.
+	Retry *RetryPolicy
+
+	// WaitOnRateLimit makes the client wait until the rate limit resets
+	// and try again when the API responds with ErrLimitReached, instead
+	// of returning the error. MaxRateLimitWait, if non-zero, caps how long
+	// the client is willing to wait for a single reset. MaxRateLimitWaits
+	// caps how many times a single request waits, and defaults to 3; once
+	// it is reached, the ErrLimitReached error is returned.
+	WaitOnRateLimit   bool
+	MaxRateLimitWait  time.Duration
+	MaxRateLimitWaits int
+
+	// Limiter, if set, throttles requests on the client side. A single
+	// Limiter may be shared between goroutines and between Clients.
+	Limiter *Limiter
+
+	// Middleware wraps every HTTP request made by the client, see
+	// Middleware for details. The first middleware in the slice is the
+	// outermost one, and sees requests first.
+	Middleware []Middleware
+
+	// Logger, if set, receives a record for every API operation. Request
+	// and response bodies are logged at debug level. The API key and the
+	// TURN credentials returned by GetInfo are never logged.
+	Logger *slog.Logger
+
+	// Tracer, if set, traces every API operation as a span, with a
+	// child span for every HTTP attempt. The trace context is propagated
+	// to the API in the W3C traceparent header.
+	Tracer Tracer
+
+	// Limits, if set, makes the client enforce the limits advertised by
+	// the /info endpoint before making requests that would exceed them.
+	Limits *LimitsGuard
 }
 
 // NewClient returns a new Client with sensible defaults, which can be used to interface
@@ -37,13 +77,30 @@ func NewClient(APIKey string) *Client {
 	}
 }
 
+// response holds the outcome of a single API call: the request that was
+// made and the status code, headers and body of the response to it.
+type response struct {
+	method     string
+	path       string
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// err returns an *APIError describing an unexpected response.
+func (r *response) err() error {
+	return newAPIError(r.method, r.path, r.statusCode, r.header, r.body)
+}
+
 // get takes a path string and performs a GET request to the specified
-// path for this client, and returns the result as a byte slice, or an
-// not-nil error if something went wrong during the request.
-func (c *Client) get(path string, page, limit int) (response []byte, statusCode int, err error) {
+// path for this client, and returns the response, or an
+// not-nil error if something went wrong during the request. The request
+// is bound to ctx, so cancelling ctx aborts it. op is the name of the
+// API operation, such as "ListMessages", and is passed on to middleware.
+func (c *Client) get(ctx context.Context, op, path string, page, limit int) (*response, error) {
 	u, err := url.Parse(c.RootURL)
 	if err != nil {
-		return
+		return nil, err
 	}
 	u.Path = path
 
@@ -58,48 +115,170 @@ func (c *Client) get(path string, page, limit int) (response []byte, statusCode
 	}
 	u.RawQuery = params.Encode()
 
-	req, err := http.NewRequest("GET", u.String(), nil)
-	req.SetBasicAuth(c.APIKey, "X")
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
-	contents, err := ioutil.ReadAll(resp.Body)
-	return contents, resp.StatusCode, err
+	return c.do(ctx, op, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(c.APIKey, "X")
+		return req, nil
+	})
 }
 
 // request takes a path string and performs a request (POST or PUT) to the specified
-// path for this client, and returns the result as a byte slice, or an
-// not-nil error if something went wrong during the request.
-func (c *Client) request(path string, method string, form url.Values) (response []byte, statusCode int, err error) {
+// path for this client, and returns the response, or an
+// not-nil error if something went wrong during the request. The request
+// is bound to ctx, so cancelling ctx aborts it. op is the name of the
+// API operation, such as "PostMessage", and is passed on to middleware.
+func (c *Client) request(ctx context.Context, op, path string, method string, form url.Values) (*response, error) {
 	u, err := url.Parse(c.RootURL)
 	if err != nil {
-		return
+		return nil, err
 	}
 	u.Path = path
-	req, err := http.NewRequest(method, u.String(), strings.NewReader(form.Encode()))
-	req.SetBasicAuth(c.APIKey, "X")
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return
+	body := form.Encode()
+	return c.do(ctx, op, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(c.APIKey, "X")
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
+}
+
+// do performs the API operation op, sending the requests built by
+// newRequest. It traces the operation with the client's Tracer, unless
+// ctx belongs to an operation started by startOperation, and logs the
+// outcome to the client's Logger.
+func (c *Client) do(ctx context.Context, op string, newRequest func() (*http.Request, error)) (*response, error) {
+	if c.Logger == nil && c.Tracer == nil {
+		return c.attempt(ctx, op, newRequest)
+	}
+	start := time.Now()
+	var span Span
+	o, _ := ctx.Value(operationKey{}).(*operation)
+	if c.Tracer != nil && o == nil {
+		ctx, span = c.Tracer.Start(ctx, op)
+	}
+	var last *http.Request
+	resp, err := c.attempt(ctx, op, func() (*http.Request, error) {
+		req, err := newRequest()
+		last = req
+		return req, err
+	})
+	if span != nil {
+		endOperationSpan(span, op, last, resp, err)
+	}
+	if o != nil {
+		o.req, o.resp = last, resp
+	}
+	if c.Logger != nil {
+		c.logOperation(ctx, op, last, resp, err, time.Since(start))
+	}
+	return resp, err
+}
+
+// attempt sends the requests built by newRequest until one succeeds or the
+// client's RetryPolicy gives up, and returns the last response.
+// newRequest is called once per attempt, so that every attempt gets a
+// fresh request body. If the request was retried and still failed, the
+// error is a *RetryError recording the number of attempts.
+//
+// Before every attempt it waits for the client's Limiter, and after every
+// attempt it records the response in the ResponseMeta attached to ctx,
+// if any.
+func (c *Client) attempt(ctx context.Context, op string, newRequest func() (*http.Request, error)) (*response, error) {
+	policy := c.Retry
+	waits := 0
+	for attempt := 1; ; attempt++ {
+		if c.Limiter != nil {
+			if err := c.Limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		actx := withAttempt(ctx, attempt)
+		var span Span
+		if c.Tracer != nil {
+			actx, span = c.Tracer.Start(actx, "HTTP "+req.Method)
+			req.Header.Set("traceparent", span.SpanContext().Traceparent())
+		}
+		req = req.WithContext(actx)
+		resp, err := c.send(op, req)
+		if span != nil {
+			endAttemptSpan(span, req, attempt, resp, err)
+		}
+
+		if err == nil {
+			rl := parseRateLimit(resp.header, time.Now())
+			if meta := responseMetaFromContext(ctx); meta != nil {
+				*meta = ResponseMeta{StatusCode: resp.statusCode, Header: resp.header, RateLimit: rl}
+			}
+			if rateLimited(resp.statusCode, resp.body) {
+				wait := rl.wait(time.Now())
+				if c.Limiter != nil {
+					c.Limiter.blockFor(wait)
+				}
+				if c.WaitOnRateLimit && (c.MaxRateLimitWait <= 0 || wait <= c.MaxRateLimitWait) && waits < c.maxRateLimitWaits() {
+					waits++
+					if err := sleep(ctx, wait); err != nil {
+						return resp, err
+					}
+					// waiting out a rate limit is not a failed attempt
+					attempt--
+					continue
+				}
+			}
+		}
+
+		retry := policy.retryable(req.Method, resp, err)
+		if !retry || attempt >= policy.maxAttempts() || ctx.Err() != nil {
+			// report the attempts made whenever they ended in an error,
+			// even if the final one was not worth retrying
+			if attempt > 1 && (err != nil || resp.statusCode >= http.StatusBadRequest) {
+				if err == nil {
+					err = resp.err()
+				}
+				err = &RetryError{Attempts: attempt, Err: err}
+			}
+			return resp, err
+		}
+		if err := sleep(ctx, policy.backoff(attempt)); err != nil {
+			return resp, &RetryError{Attempts: attempt, Err: err}
+		}
 	}
-	defer resp.Body.Close()
-	contents, err := ioutil.ReadAll(resp.Body)
-	return contents, resp.StatusCode, err
 }
 
-// handleError is a helper that unmarshals a json
-// response byte slice into an Error struct and returns
-// it as the error interface.
-func handleError(b []byte) error {
-	var fullErr Error
-	err := json.Unmarshal(b, &fullErr)
+// maxRateLimitWaits returns how many times a request may wait out a rate
+// limit.
+func (c *Client) maxRateLimitWaits() int {
+	if c.MaxRateLimitWaits <= 0 {
+		return defaultMaxRateLimitWaits
+	}
+	return c.MaxRateLimitWaits
+}
+
+// send performs a single HTTP round trip through the client's middleware
+// and reads the full response body.
+func (c *Client) send(op string, req *http.Request) (*response, error) {
+	resp, err := c.handler()(op, req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return fullErr
+	defer resp.Body.Close()
+	contents, err := ioutil.ReadAll(resp.Body)
+	return &response{
+		method:     req.Method,
+		path:       req.URL.Path,
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+		body:       contents,
+	}, err
 }
 
 /*************************************************************************
@@ -113,33 +292,196 @@ func handleError(b []byte) error {
 // The messages are returned in reverse date order by default. If page
 // or limit is set to zero, the defaults are used.
 func (c *Client) ListMessages(page, limit int) ([]Message, error) {
+	return c.ListMessagesContext(context.Background(), page, limit)
+}
+
+// ListMessagesContext is like ListMessages, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) ListMessagesContext(ctx context.Context, page, limit int) ([]Message, error) {
+	return c.listMessages(ctx, "ListMessages", page, limit)
+}
+
+// listMessages lists a page of messages on behalf of the operation op.
+func (c *Client) listMessages(ctx context.Context, op string, page, limit int) ([]Message, error) {
 	p := "/messages"
-	b, status, err := c.get(p, page, limit)
+	resp, err := c.get(ctx, op, p, page, limit)
 	if err != nil {
 		return nil, err
 	}
-	if status != http.StatusOK {
-		return nil, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return nil, resp.err()
 	}
 	var m []Message
-	err = json.Unmarshal(b, &m)
+	err = json.Unmarshal(resp.body, &m)
 
 	return m, err
 }
 
+// ListMessagesOptions filters the messages returned by
+// Client.ListMessagesWithOptions. Zero fields do not filter.
+type ListMessagesOptions struct {
+	StreamID       int       // Only messages in this stream
+	ConversationID int       // Only messages in this conversation
+	AuthorID       int       // Only messages by this user
+	Since          time.Time // Only messages created at or after this time
+	Until          time.Time // Only messages created before this time
+	BeforeID       int       // Only messages with a lower ID
+	AfterID        int       // Only messages with a higher ID
+}
+
+// Validate checks the options before a request is made, and returns an
+// ErrInvalidParam error if a range ends before it starts. A range that
+// merely holds no messages, such as BeforeID 5 and AfterID 4, is valid.
+func (o *ListMessagesOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if !o.Since.IsZero() && !o.Until.IsZero() && o.Until.Before(o.Since) {
+		return invalidParam("until", "Until must not be before since")
+	}
+	if o.BeforeID != 0 && o.AfterID != 0 && o.BeforeID <= o.AfterID {
+		return invalidParam("before_id", "Before ID must be greater than after ID")
+	}
+	return nil
+}
+
+// Match reports whether m passes the filters of the options.
+func (o *ListMessagesOptions) Match(m Message) bool {
+	if o == nil {
+		return true
+	}
+	switch {
+	case o.StreamID != 0 && m.StreamID != o.StreamID,
+		o.ConversationID != 0 && (m.ConversationID == nil || *m.ConversationID != o.ConversationID),
+		o.AuthorID != 0 && m.Author.ID != o.AuthorID,
+		!o.Since.IsZero() && m.CreatedAt.Before(o.Since),
+		!o.Until.IsZero() && !m.CreatedAt.Before(o.Until),
+		o.BeforeID != 0 && m.ID >= o.BeforeID,
+		o.AfterID != 0 && m.ID <= o.AfterID:
+		return false
+	}
+	return true
+}
+
+// Past reports whether m, and so every message listed after it, is older
+// than the options allow. Since messages are listed newest first, listing
+// can stop at the first message that is past.
+func (o *ListMessagesOptions) Past(m Message) bool {
+	return o != nil && (!o.Since.IsZero() && m.CreatedAt.Before(o.Since) || o.AfterID != 0 && m.ID <= o.AfterID)
+}
+
+// ListMessagesWithOptions is like ListMessages, but only returns the
+// messages that match options. If options.StreamID is set, the page is
+// fetched from GET /streams/:id/messages; if the API does not provide
+// that endpoint, the page is fetched from GET /messages instead, once
+// the stream is known to exist. All other filters are applied by the
+// client to the page, so a page may hold fewer than limit messages even
+// if more pages follow. Use AllMessagesWithOptions to iterate over all
+// matching messages.
+func (c *Client) ListMessagesWithOptions(page, limit int, options *ListMessagesOptions) ([]Message, error) {
+	return c.ListMessagesWithOptionsContext(context.Background(), page, limit, options)
+}
+
+// ListMessagesWithOptionsContext is like ListMessagesWithOptions, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) ListMessagesWithOptionsContext(ctx context.Context, page, limit int, options *ListMessagesOptions) ([]Message, error) {
+	return c.listMessagesWithOptions(ctx, "ListMessagesWithOptions", page, limit, options)
+}
+
+// listMessagesWithOptions lists a page of the messages that match options
+// on behalf of the operation op.
+func (c *Client) listMessagesWithOptions(ctx context.Context, op string, page, limit int, options *ListMessagesOptions) ([]Message, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	streamID := 0
+	if options != nil {
+		streamID = options.StreamID
+	}
+	msgs, _, err := c.listMessagesPage(ctx, op, streamID, true, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	matched := []Message{}
+	for _, m := range msgs {
+		if options.Match(m) {
+			matched = append(matched, m)
+		}
+	}
+	return matched, nil
+}
+
+// ListStreamMessages returns the messages in the chat stream with the
+// given ID, newest first. It is short for ListMessagesWithOptions with
+// only the StreamID option set.
+func (c *Client) ListStreamMessages(streamID, page, limit int) ([]Message, error) {
+	return c.ListStreamMessagesContext(context.Background(), streamID, page, limit)
+}
+
+// ListStreamMessagesContext is like ListStreamMessages, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) ListStreamMessagesContext(ctx context.Context, streamID, page, limit int) ([]Message, error) {
+	return c.listMessagesWithOptions(ctx, "ListStreamMessages", page, limit, &ListMessagesOptions{StreamID: streamID})
+}
+
+// listMessagesPage fetches a page of messages from the messages of the
+// stream if streamID is set and nested is true, or from all messages
+// otherwise. It reports whether the nested endpoint was used, which is
+// not the case if the API responded to it with 404 Not Found.
+// All requests are made, and traced, as part of the operation op.
+func (c *Client) listMessagesPage(ctx context.Context, op string, streamID int, nested bool, page, limit int) (msgs []Message, ok bool, err error) {
+	ctx, end := c.startOperation(ctx, op)
+	defer func() { end(err) }()
+	if streamID == 0 || !nested {
+		msgs, err := c.listMessages(ctx, op, page, limit)
+		return msgs, false, err
+	}
+	resp, err := c.get(ctx, op, fmt.Sprintf("/streams/%d/messages", streamID), page, limit)
+	if resp != nil && resp.statusCode == http.StatusNotFound {
+		// a 404 with an error body comes from the API and means the stream
+		// does not exist; without one, the route itself is unknown
+		if hasErrorBody(resp.body) {
+			if err == nil {
+				err = resp.err()
+			}
+			return nil, false, err
+		}
+		if _, err := c.getStream(ctx, op, streamID); err != nil {
+			return nil, false, err
+		}
+		msgs, err := c.listMessages(ctx, op, page, limit)
+		return msgs, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.statusCode != http.StatusOK {
+		return nil, false, resp.err()
+	}
+	var m []Message
+	err = json.Unmarshal(resp.body, &m)
+	return m, true, err
+}
+
 // GetMessage returns the reponse for GET /message.
 // It retrieves the details of a message and any nested attachments.
 func (c *Client) GetMessage(id int) (Message, error) {
+	return c.GetMessageContext(context.Background(), id)
+}
+
+// GetMessageContext is like GetMessage, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) GetMessageContext(ctx context.Context, id int) (Message, error) {
 	p := fmt.Sprintf("/messages/%d", id)
-	b, status, err := c.get(p, 0, 0)
+	resp, err := c.get(ctx, "GetMessage", p, 0, 0)
 	if err != nil {
 		return Message{}, err
 	}
-	if status != http.StatusOK {
-		return Message{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return Message{}, resp.err()
 	}
 	var m Message
-	err = json.Unmarshal(b, &m)
+	err = json.Unmarshal(resp.body, &m)
 	return m, err
 }
 
@@ -162,7 +504,16 @@ type PostMessageOptions struct {
 // user_name and user_id with a given users name and id.
 //
 //   @(user_name)[user:user_id]
+//
+// FormatMention and User.MentionToken build these tokens, escaping the
+// name where needed.
 func (c *Client) PostMessage(streamID int, text string, options *PostMessageOptions) (Message, error) {
+	return c.PostMessageContext(context.Background(), streamID, text, options)
+}
+
+// PostMessageContext is like PostMessage, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) PostMessageContext(ctx context.Context, streamID int, text string, options *PostMessageOptions) (Message, error) {
 	form := url.Values{}
 	form.Add("stream_id", fmt.Sprintf("%d", streamID))
 	form.Add("text", text)
@@ -174,15 +525,15 @@ func (c *Client) PostMessage(streamID int, text string, options *PostMessageOpti
 			form.Add("parse", fmt.Sprintf("%t", options.Parse))
 		}
 	}
-	b, status, err := c.request("/messages", "POST", form)
+	resp, err := c.request(ctx, "PostMessage", "/messages", "POST", form)
 	if err != nil {
 		return Message{}, err
 	}
-	if status != http.StatusCreated {
-		return Message{}, handleError(b)
+	if resp.statusCode != http.StatusCreated {
+		return Message{}, resp.err()
 	}
 	var m Message
-	err = json.Unmarshal(b, &m)
+	err = json.Unmarshal(resp.body, &m)
 	return m, err
 }
 
@@ -190,17 +541,23 @@ func (c *Client) PostMessage(streamID int, text string, options *PostMessageOpti
 // of the parameters passed. Note that changes made via the API will
 // be immediately reflected in the interface of all connected clients.
 func (c *Client) UpdateMessage(id int, text string) (Message, error) {
+	return c.UpdateMessageContext(context.Background(), id, text)
+}
+
+// UpdateMessageContext is like UpdateMessage, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) UpdateMessageContext(ctx context.Context, id int, text string) (Message, error) {
 	form := url.Values{}
 	form.Add("text", text)
-	b, status, err := c.request(fmt.Sprintf("/messages/%d", id), "PUT", form)
+	resp, err := c.request(ctx, "UpdateMessage", fmt.Sprintf("/messages/%d", id), "PUT", form)
 	if err != nil {
 		return Message{}, err
 	}
-	if status != http.StatusOK {
-		return Message{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return Message{}, resp.err()
 	}
 	var m Message
-	err = json.Unmarshal(b, &m)
+	err = json.Unmarshal(resp.body, &m)
 	return m, err
 }
 
@@ -208,12 +565,18 @@ func (c *Client) UpdateMessage(id int, text string) (Message, error) {
 // conversation flow is preserved the message will be replaced with a
 // "This message has been removed" note in the stream.
 func (c *Client) DeleteMessage(id int) error {
-	b, status, err := c.request(fmt.Sprintf("/messages/%d", id), "DELETE", url.Values{})
+	return c.DeleteMessageContext(context.Background(), id)
+}
+
+// DeleteMessageContext is like DeleteMessage, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) DeleteMessageContext(ctx context.Context, id int) error {
+	resp, err := c.request(ctx, "DeleteMessage", fmt.Sprintf("/messages/%d", id), "DELETE", url.Values{})
 	if err != nil {
 		return err
 	}
-	if status != http.StatusNoContent {
-		return handleError(b)
+	if resp.statusCode != http.StatusNoContent {
+		return resp.err()
 	}
 	return nil
 }
@@ -228,16 +591,22 @@ func (c *Client) DeleteMessage(id int) error {
 // It returns a list of all streams in the current organization.
 // The streams are returned in sorted alphabetical order by default.
 func (c *Client) ListStreams(page, limit int) ([]Stream, error) {
+	return c.ListStreamsContext(context.Background(), page, limit)
+}
+
+// ListStreamsContext is like ListStreams, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) ListStreamsContext(ctx context.Context, page, limit int) ([]Stream, error) {
 	p := "/streams"
-	b, status, err := c.get(p, page, limit)
+	resp, err := c.get(ctx, "ListStreams", p, page, limit)
 	if err != nil {
 		return nil, err
 	}
-	if status != http.StatusOK {
-		return nil, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return nil, resp.err()
 	}
 	var s []Stream
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
@@ -246,16 +615,28 @@ func (c *Client) ListStreams(page, limit int) ([]Stream, error) {
 // has access to. Supply an ID and Sqwiggle will return
 // the corresponding chat stream object.
 func (c *Client) GetStream(id int) (Stream, error) {
+	return c.GetStreamContext(context.Background(), id)
+}
+
+// GetStreamContext is like GetStream, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) GetStreamContext(ctx context.Context, id int) (Stream, error) {
+	return c.getStream(ctx, "GetStream", id)
+}
+
+// getStream gets the stream with the given ID on behalf of the operation
+// op.
+func (c *Client) getStream(ctx context.Context, op string, id int) (Stream, error) {
 	p := fmt.Sprintf("/streams/%d", id)
-	b, status, err := c.get(p, 0, 0)
+	resp, err := c.get(ctx, op, p, 0, 0)
 	if err != nil {
 		return Stream{}, err
 	}
-	if status != http.StatusOK {
-		return Stream{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return Stream{}, resp.err()
 	}
 	var s Stream
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
@@ -263,18 +644,34 @@ func (c *Client) GetStream(id int) (Stream, error) {
 // Streams can be created from the app interfaces, or programatically via the API.
 // Sqwiggle currently has no restrictions on the number of chat streams
 // you can create within an organization.
+//
+// If the client has a LimitsGuard, PostStream fails with an ErrLimitReached
+// error, without creating the stream, when the organization already has
+// as many streams as the max_free_streams advertised by /info.
 func (c *Client) PostStream(name string) (Stream, error) {
+	return c.PostStreamContext(context.Background(), name)
+}
+
+// PostStreamContext is like PostStream, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) PostStreamContext(ctx context.Context, name string) (s Stream, err error) {
+	ctx, end := c.startOperation(ctx, "PostStream")
+	defer func() { end(err) }()
+	if c.Limits != nil {
+		if err := c.checkStreamLimit(ctx); err != nil {
+			return Stream{}, err
+		}
+	}
 	form := url.Values{}
 	form.Add("name", name)
-	b, status, err := c.request("/streams", "POST", form)
+	resp, err := c.request(ctx, "PostStream", "/streams", "POST", form)
 	if err != nil {
 		return Stream{}, err
 	}
-	if status != http.StatusCreated {
-		return Stream{}, handleError(b)
+	if resp.statusCode != http.StatusCreated {
+		return Stream{}, resp.err()
 	}
-	var s Stream
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
@@ -282,28 +679,40 @@ func (c *Client) PostStream(name string) (Stream, error) {
 // the parameters passed. At this time the only parameter that can be
 // changed is the name, paths will be automatically generated.
 func (c *Client) UpdateStream(id int, name string) (Stream, error) {
+	return c.UpdateStreamContext(context.Background(), id, name)
+}
+
+// UpdateStreamContext is like UpdateStream, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) UpdateStreamContext(ctx context.Context, id int, name string) (Stream, error) {
 	form := url.Values{}
 	form.Add("name", name)
-	b, status, err := c.request(fmt.Sprintf("/streams/%d", id), "PUT", form)
+	resp, err := c.request(ctx, "UpdateStream", fmt.Sprintf("/streams/%d", id), "PUT", form)
 	if err != nil {
 		return Stream{}, err
 	}
-	if status != http.StatusOK {
-		return Stream{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return Stream{}, resp.err()
 	}
 	var m Stream
-	err = json.Unmarshal(b, &m)
+	err = json.Unmarshal(resp.body, &m)
 	return m, err
 }
 
 // DeleteStream removes the chat stream from the organisation.
 func (c *Client) DeleteStream(id int) error {
-	b, status, err := c.request(fmt.Sprintf("/streams/%d", id), "DELETE", url.Values{})
+	return c.DeleteStreamContext(context.Background(), id)
+}
+
+// DeleteStreamContext is like DeleteStream, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) DeleteStreamContext(ctx context.Context, id int) error {
+	resp, err := c.request(ctx, "DeleteStream", fmt.Sprintf("/streams/%d", id), "DELETE", url.Values{})
 	if err != nil {
 		return err
 	}
-	if status != http.StatusNoContent {
-		return handleError(b)
+	if resp.statusCode != http.StatusNoContent {
+		return resp.err()
 	}
 	return nil
 }
@@ -317,16 +726,22 @@ func (c *Client) DeleteStream(id int) error {
 // ListUsers returns the reponse for GET /users.
 // It returns a list of all users in the current organization.
 func (c *Client) ListUsers(page, limit int) ([]User, error) {
+	return c.ListUsersContext(context.Background(), page, limit)
+}
+
+// ListUsersContext is like ListUsers, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) ListUsersContext(ctx context.Context, page, limit int) ([]User, error) {
 	p := "/users"
-	b, status, err := c.get(p, page, limit)
+	resp, err := c.get(ctx, "ListUsers", p, page, limit)
 	if err != nil {
 		return nil, err
 	}
-	if status != http.StatusOK {
-		return nil, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return nil, resp.err()
 	}
 	var s []User
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
@@ -335,45 +750,108 @@ func (c *Client) ListUsers(page, limit int) ([]User, error) {
 // has access to. Supply an ID and Sqwiggle will return
 // the corresponding chat user object.
 func (c *Client) GetUser(id int) (User, error) {
+	return c.GetUserContext(context.Background(), id)
+}
+
+// GetUserContext is like GetUser, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) GetUserContext(ctx context.Context, id int) (User, error) {
 	p := fmt.Sprintf("/users/%d", id)
-	b, status, err := c.get(p, 0, 0)
+	resp, err := c.get(ctx, "GetUser", p, 0, 0)
 	if err != nil {
 		return User{}, err
 	}
-	if status != http.StatusOK {
-		return User{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return User{}, resp.err()
 	}
 	var s User
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
-// UpdateUser updates the specified user by setting the values of the parameters passed.
-// Any parameters not provided will be left unchanged, and unrecognised parameters will
-// result in the request returning an error response.
-//
-// The parameters that may be set are:
-//
-//    name	The users full display name
-//    email	The users email address
-//    time_zone	The users time zone (in rails format)
-//    avatar	A URL pointing to the users avatar, this must reside on Sqwiggle's servers
-//    status	Status enum may be set to one of 'busy', 'available' or 'offline'
-//    message	A custom message which will be displayed to other users
-//    snapshot	A URL pointing to the users current snapshot
-//    snapshot_interval	An integer specifying how often an automatic snapshot should be taken, must be set to 0 or greater than 59
-//
-// All parameters are optional.
-func (c *Client) UpdateUser(id int, values url.Values) (User, error) {
-	b, status, err := c.request(fmt.Sprintf("/users/%d", id), "PUT", values)
+// UpdateUserOptions defines the parameters that Client.UpdateUser may set.
+// Only the fields that are not nil are sent, and all other attributes of
+// the user are left unchanged. The String and Int functions help to fill
+// in the pointer fields.
+type UpdateUserOptions struct {
+	Name             *string     // The users full display name
+	Email            *string     // The users email address
+	TimeZone         *string     // The users time zone (in rails format)
+	Avatar           *string     // A URL pointing to the users avatar, this must reside on Sqwiggle's servers
+	Status           *UserStatus // One of StatusBusy, StatusAvailable or StatusOffline
+	Message          *string     // A custom message which will be displayed to other users
+	Snapshot         *string     // A URL pointing to the users current snapshot
+	SnapshotInterval *int        // How often an automatic snapshot should be taken, must be 0 or greater than 59
+}
+
+// Validate checks the options before they are sent to the API, and
+// returns an ErrInvalidParam error for the first invalid parameter.
+func (o *UpdateUserOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Name != nil && *o.Name == "" {
+		return invalidParam("name", "Name must not be empty")
+	}
+	if o.Email != nil && !strings.Contains(*o.Email, "@") {
+		return invalidParam("email", "Email must be a valid email address")
+	}
+	if o.Status != nil {
+		switch *o.Status {
+		case StatusBusy, StatusAvailable, StatusOffline:
+		default:
+			return invalidParam("status", "Status must be one of busy, available or offline")
+		}
+	}
+	if o.SnapshotInterval != nil && *o.SnapshotInterval != 0 && *o.SnapshotInterval < 60 {
+		return invalidParam("snapshot_interval", "Snapshot interval must be 0 or greater than 59")
+	}
+	return nil
+}
+
+// values returns the parameters to send for the options.
+func (o *UpdateUserOptions) values() url.Values {
+	form := url.Values{}
+	if o == nil {
+		return form
+	}
+	setString(form, "name", o.Name)
+	setString(form, "email", o.Email)
+	setString(form, "time_zone", o.TimeZone)
+	setString(form, "avatar", o.Avatar)
+	if o.Status != nil {
+		form.Set("status", string(*o.Status))
+	}
+	setString(form, "message", o.Message)
+	setString(form, "snapshot", o.Snapshot)
+	if o.SnapshotInterval != nil {
+		form.Set("snapshot_interval", strconv.Itoa(*o.SnapshotInterval))
+	}
+	return form
+}
+
+// UpdateUser updates the specified user by setting the parameters in options.
+// Any parameters not provided will be left unchanged. The options are
+// validated before the request is made, see UpdateUserOptions.Validate.
+func (c *Client) UpdateUser(id int, options *UpdateUserOptions) (User, error) {
+	return c.UpdateUserContext(context.Background(), id, options)
+}
+
+// UpdateUserContext is like UpdateUser, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) UpdateUserContext(ctx context.Context, id int, options *UpdateUserOptions) (User, error) {
+	if err := options.Validate(); err != nil {
+		return User{}, err
+	}
+	resp, err := c.request(ctx, "UpdateUser", fmt.Sprintf("/users/%d", id), "PUT", options.values())
 	if err != nil {
 		return User{}, err
 	}
-	if status != http.StatusOK {
-		return User{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return User{}, resp.err()
 	}
 	var m User
-	err = json.Unmarshal(b, &m)
+	err = json.Unmarshal(resp.body, &m)
 	return m, err
 }
 
@@ -387,16 +865,22 @@ func (c *Client) UpdateUser(id int, values url.Values) (User, error) {
 // At this time each user can only belong to a single organization and all
 // API requests are scoped by a single organization.
 func (c *Client) ListOrganizations(page, limit int) ([]Organization, error) {
+	return c.ListOrganizationsContext(context.Background(), page, limit)
+}
+
+// ListOrganizationsContext is like ListOrganizations, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) ListOrganizationsContext(ctx context.Context, page, limit int) ([]Organization, error) {
 	p := "/organizations"
-	b, status, err := c.get(p, page, limit)
+	resp, err := c.get(ctx, "ListOrganizations", p, page, limit)
 	if err != nil {
 		return nil, err
 	}
-	if status != http.StatusOK {
-		return nil, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return nil, resp.err()
 	}
 	var o []Organization
-	err = json.Unmarshal(b, &o)
+	err = json.Unmarshal(resp.body, &o)
 	return o, err
 }
 
@@ -405,35 +889,73 @@ func (c *Client) ListOrganizations(page, limit int) ([]Organization, error) {
 // has access to. Supply an ID and Sqwiggle will return
 // the corresponding chat user object.
 func (c *Client) GetOrganization(id int) (Organization, error) {
+	return c.GetOrganizationContext(context.Background(), id)
+}
+
+// GetOrganizationContext is like GetOrganization, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) GetOrganizationContext(ctx context.Context, id int) (Organization, error) {
 	p := fmt.Sprintf("/organizations/%d", id)
-	b, status, err := c.get(p, 0, 0)
+	resp, err := c.get(ctx, "GetOrganization", p, 0, 0)
 	if err != nil {
 		return Organization{}, err
 	}
-	if status != http.StatusOK {
-		return Organization{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return Organization{}, resp.err()
 	}
 	var o Organization
-	err = json.Unmarshal(b, &o)
+	err = json.Unmarshal(resp.body, &o)
 	return o, err
 }
 
-// UpdateOrganization updates the specified organization by setting the values of the parameters passed.
+// UpdateOrganizationOptions defines the parameters that
+// Client.UpdateOrganization may set. Only the fields that are not nil are
+// sent. DiffOrganization builds the options from an old and new version
+// of an organization.
+type UpdateOrganizationOptions struct {
+	Name *string // The organizations name
+}
+
+// Validate checks the options before they are sent to the API, and
+// returns an ErrInvalidParam error for the first invalid parameter.
+func (o *UpdateOrganizationOptions) Validate() error {
+	if o != nil && o.Name != nil && *o.Name == "" {
+		return invalidParam("name", "Name must not be empty")
+	}
+	return nil
+}
+
+// values returns the parameters to send for the options.
+func (o *UpdateOrganizationOptions) values() url.Values {
+	form := url.Values{}
+	if o != nil {
+		setString(form, "name", o.Name)
+	}
+	return form
+}
+
+// UpdateOrganization updates the specified organization by setting the parameters in options.
 // At this time the only parameter that can be changed is the organization name,
 // paths will be automatically generated.
-//
-// Optional parameters are:
-//   name	The oranizations name
-func (c *Client) UpdateOrganization(id int, values url.Values) (Organization, error) {
-	b, status, err := c.request(fmt.Sprintf("/organizations/%d", id), "PUT", values)
+func (c *Client) UpdateOrganization(id int, options *UpdateOrganizationOptions) (Organization, error) {
+	return c.UpdateOrganizationContext(context.Background(), id, options)
+}
+
+// UpdateOrganizationContext is like UpdateOrganization, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) UpdateOrganizationContext(ctx context.Context, id int, options *UpdateOrganizationOptions) (Organization, error) {
+	if err := options.Validate(); err != nil {
+		return Organization{}, err
+	}
+	resp, err := c.request(ctx, "UpdateOrganization", fmt.Sprintf("/organizations/%d", id), "PUT", options.values())
 	if err != nil {
 		return Organization{}, err
 	}
-	if status != http.StatusOK {
-		return Organization{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return Organization{}, resp.err()
 	}
 	var o Organization
-	err = json.Unmarshal(b, &o)
+	err = json.Unmarshal(resp.body, &o)
 	return o, err
 }
 
@@ -444,17 +966,45 @@ func (c *Client) UpdateOrganization(id int, values url.Values) (Organization, er
 *************************************************************************/
 
 // GetInfo returns the reponse for GET /info. This is an unstructured response,
-// so this endpoint just returns the raw byte slice.
+// so this endpoint just returns the raw byte slice. Use GetInfoTyped to have
+// it decoded into an Info.
 func (c *Client) GetInfo() ([]byte, error) {
+	return c.GetInfoContext(context.Background())
+}
+
+// GetInfoContext is like GetInfo, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) GetInfoContext(ctx context.Context) ([]byte, error) {
 	p := fmt.Sprintf("/info")
-	b, status, err := c.get(p, 0, 0)
+	resp, err := c.get(ctx, "GetInfo", p, 0, 0)
 	if err != nil {
 		return nil, err
 	}
-	if status != http.StatusOK {
-		return nil, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return nil, resp.err()
+	}
+	return resp.body, nil
+}
+
+// GetInfoTyped returns the response for GET /info, decoded into an Info.
+// Any keys that Info does not know about are kept in its Extra fields.
+func (c *Client) GetInfoTyped() (Info, error) {
+	return c.GetInfoTypedContext(context.Background())
+}
+
+// GetInfoTypedContext is like GetInfoTyped, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) GetInfoTypedContext(ctx context.Context) (Info, error) {
+	resp, err := c.get(ctx, "GetInfoTyped", "/info", 0, 0)
+	if err != nil {
+		return Info{}, err
+	}
+	if resp.statusCode != http.StatusOK {
+		return Info{}, resp.err()
 	}
-	return b, nil
+	var i Info
+	err = json.Unmarshal(resp.body, &i)
+	return i, err
 }
 
 /*************************************************************************
@@ -465,32 +1015,44 @@ func (c *Client) GetInfo() ([]byte, error) {
 
 // ListConversations returns a list of all conversations the current token has access to.
 func (c *Client) ListConversations(page, limit int) ([]Conversation, error) {
+	return c.ListConversationsContext(context.Background(), page, limit)
+}
+
+// ListConversationsContext is like ListConversations, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) ListConversationsContext(ctx context.Context, page, limit int) ([]Conversation, error) {
 	p := "/conversations"
-	b, status, err := c.get(p, page, limit)
+	resp, err := c.get(ctx, "ListConversations", p, page, limit)
 	if err != nil {
 		return nil, err
 	}
-	if status != http.StatusOK {
-		return nil, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return nil, resp.err()
 	}
 	var o []Conversation
-	err = json.Unmarshal(b, &o)
+	err = json.Unmarshal(resp.body, &o)
 	return o, err
 }
 
 // GetConversation retrieves the details of a specific conversation
 // provided it is accessible via the provided token.
 func (c *Client) GetConversation(id int) (Conversation, error) {
+	return c.GetConversationContext(context.Background(), id)
+}
+
+// GetConversationContext is like GetConversation, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) GetConversationContext(ctx context.Context, id int) (Conversation, error) {
 	p := fmt.Sprintf("/conversations/%d", id)
-	b, status, err := c.get(p, 0, 0)
+	resp, err := c.get(ctx, "GetConversation", p, 0, 0)
 	if err != nil {
 		return Conversation{}, err
 	}
-	if status != http.StatusOK {
-		return Conversation{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return Conversation{}, resp.err()
 	}
 	var o Conversation
-	err = json.Unmarshal(b, &o)
+	err = json.Unmarshal(resp.body, &o)
 	return o, err
 }
 
@@ -503,32 +1065,44 @@ func (c *Client) GetConversation(id int) (Conversation, error) {
 // ListInvites returns a list of all outstanging invites in
 // the current organization.
 func (c *Client) ListInvites(page, limit int) ([]Invite, error) {
+	return c.ListInvitesContext(context.Background(), page, limit)
+}
+
+// ListInvitesContext is like ListInvites, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) ListInvitesContext(ctx context.Context, page, limit int) ([]Invite, error) {
 	p := "/invites"
-	b, status, err := c.get(p, page, limit)
+	resp, err := c.get(ctx, "ListInvites", p, page, limit)
 	if err != nil {
 		return nil, err
 	}
-	if status != http.StatusOK {
-		return nil, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return nil, resp.err()
 	}
 	var s []Invite
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
 // GetInvite retrieves the details of any invite that has been
 // previously created. Supply an invite ID to get details of the invite.
 func (c *Client) GetInvite(id int) (Invite, error) {
+	return c.GetInviteContext(context.Background(), id)
+}
+
+// GetInviteContext is like GetInvite, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) GetInviteContext(ctx context.Context, id int) (Invite, error) {
 	p := fmt.Sprintf("/invites/%d", id)
-	b, status, err := c.get(p, 0, 0)
+	resp, err := c.get(ctx, "GetInvite", p, 0, 0)
 	if err != nil {
 		return Invite{}, err
 	}
-	if status != http.StatusOK {
-		return Invite{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return Invite{}, resp.err()
 	}
 	var s Invite
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
@@ -538,17 +1112,23 @@ func (c *Client) GetInvite(id int) (Invite, error) {
 // creating invites for test purposes, abuse of this API may result
 // in your account becoming blocked.
 func (c *Client) PostInvite(email string) (Invite, error) {
+	return c.PostInviteContext(context.Background(), email)
+}
+
+// PostInviteContext is like PostInvite, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) PostInviteContext(ctx context.Context, email string) (Invite, error) {
 	form := url.Values{}
 	form.Add("email", email)
-	b, status, err := c.request("/invites", "POST", form)
+	resp, err := c.request(ctx, "PostInvite", "/invites", "POST", form)
 	if err != nil {
 		return Invite{}, err
 	}
-	if status != http.StatusCreated {
-		return Invite{}, handleError(b)
+	if resp.statusCode != http.StatusCreated {
+		return Invite{}, resp.err()
 	}
 	var s Invite
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
@@ -556,12 +1136,18 @@ func (c *Client) PostInvite(email string) (Invite, error) {
 // result in the invite no longer working should the recipient click on the
 // link contained in the invite email.
 func (c *Client) DeleteInvite(id int) error {
-	b, status, err := c.request(fmt.Sprintf("/invites/%d", id), "DELETE", url.Values{})
+	return c.DeleteInviteContext(context.Background(), id)
+}
+
+// DeleteInviteContext is like DeleteInvite, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) DeleteInviteContext(ctx context.Context, id int) error {
+	resp, err := c.request(ctx, "DeleteInvite", fmt.Sprintf("/invites/%d", id), "DELETE", url.Values{})
 	if err != nil {
 		return err
 	}
-	if status != http.StatusNoContent {
-		return handleError(b)
+	if resp.statusCode != http.StatusNoContent {
+		return resp.err()
 	}
 	return nil
 }
@@ -575,32 +1161,44 @@ func (c *Client) DeleteInvite(id int) error {
 // ListAttachments returns a list of all attachments in the current organization.
 // The attachments are returned in reverse date order by default.
 func (c *Client) ListAttachments(page, limit int) ([]Attachment, error) {
+	return c.ListAttachmentsContext(context.Background(), page, limit)
+}
+
+// ListAttachmentsContext is like ListAttachments, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) ListAttachmentsContext(ctx context.Context, page, limit int) ([]Attachment, error) {
 	p := "/attachments"
-	b, status, err := c.get(p, page, limit)
+	resp, err := c.get(ctx, "ListAttachments", p, page, limit)
 	if err != nil {
 		return nil, err
 	}
-	if status != http.StatusOK {
-		return nil, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return nil, resp.err()
 	}
 	var s []Attachment
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
 // GetAttachment retrieves the details of a message attachment. There are many
 // different types of attachments and each type may return different fields in the response.
 func (c *Client) GetAttachment(id int) (Attachment, error) {
+	return c.GetAttachmentContext(context.Background(), id)
+}
+
+// GetAttachmentContext is like GetAttachment, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) GetAttachmentContext(ctx context.Context, id int) (Attachment, error) {
 	p := fmt.Sprintf("/attachments/%d", id)
-	b, status, err := c.get(p, 0, 0)
+	resp, err := c.get(ctx, "GetAttachment", p, 0, 0)
 	if err != nil {
 		return Attachment{}, err
 	}
-	if status != http.StatusOK {
-		return Attachment{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return Attachment{}, resp.err()
 	}
 	var s Attachment
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
@@ -609,52 +1207,104 @@ func (c *Client) GetAttachment(id int) (Attachment, error) {
 // Sqwiggle currently has no restrictions on the number of chat attachments
 // you can create within an organization.
 func (c *Client) PostAttachment(name string) (Attachment, error) {
+	return c.PostAttachmentContext(context.Background(), name)
+}
+
+// PostAttachmentContext is like PostAttachment, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) PostAttachmentContext(ctx context.Context, name string) (Attachment, error) {
 	form := url.Values{}
 	form.Add("name", name)
-	b, status, err := c.request("/attachments", "POST", form)
+	resp, err := c.request(ctx, "PostAttachment", "/attachments", "POST", form)
 	if err != nil {
 		return Attachment{}, err
 	}
-	if status != http.StatusCreated {
-		return Attachment{}, handleError(b)
+	if resp.statusCode != http.StatusCreated {
+		return Attachment{}, resp.err()
 	}
 	var s Attachment
-	err = json.Unmarshal(b, &s)
+	err = json.Unmarshal(resp.body, &s)
 	return s, err
 }
 
-// UpdateAttachment updates the specified attachment by setting the values of
-// the parameters passed. Note that changes made via the API will be immediately
+// UpdateAttachmentOptions defines the parameters that Client.UpdateAttachment
+// may set. Only the fields that are not nil are sent. DiffAttachment builds
+// the options from an old and new version of an attachment.
+type UpdateAttachmentOptions struct {
+	Title       *string // A title for the attachment, for example a filename or webpage title
+	Description *string // A description of the attachment, for example a web page summary
+	URL         *string // The URL of the attachment, this may not reside on Sqwiggle's servers
+	Image       *string // The URL for an optional preview image
+	Status      *string // If an upload, either AttachmentPending or AttachmentUploaded
+}
+
+// Validate checks the options before they are sent to the API, and
+// returns an ErrInvalidParam error for the first invalid parameter.
+func (o *UpdateAttachmentOptions) Validate() error {
+	if o == nil || o.Status == nil {
+		return nil
+	}
+	switch *o.Status {
+	case AttachmentPending, AttachmentUploaded:
+		return nil
+	}
+	return invalidParam("status", "Status must be one of pending or uploaded")
+}
+
+// values returns the parameters to send for the options.
+func (o *UpdateAttachmentOptions) values() url.Values {
+	form := url.Values{}
+	if o == nil {
+		return form
+	}
+	setString(form, "title", o.Title)
+	setString(form, "description", o.Description)
+	setString(form, "url", o.URL)
+	setString(form, "image", o.Image)
+	setString(form, "status", o.Status)
+	return form
+}
+
+// UpdateAttachment updates the specified attachment by setting the parameters
+// in options. Note that changes made via the API will be immediately
 // reflected in the interface of all connected clients.
-//
-// Supported values are all optional:
-//   title	A title for the attachment, for example a filename or webpage title
-//   description	A description of the attachment, for example a web page summary
-//   url	The URL of the attachment, this may not reside on Sqwiggle's servers
-//   image	The URL for an optional preview image
-//   status	If an upload, this string denotes whether the upload is 'pending' or 'uploaded'. (Null if not an upload E.G. a link attachment)
-func (c *Client) UpdateAttachment(id int, form url.Values) (Attachment, error) {
-	b, status, err := c.request(fmt.Sprintf("/attachments/%d", id), "PUT", form)
+func (c *Client) UpdateAttachment(id int, options *UpdateAttachmentOptions) (Attachment, error) {
+	return c.UpdateAttachmentContext(context.Background(), id, options)
+}
+
+// UpdateAttachmentContext is like UpdateAttachment, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) UpdateAttachmentContext(ctx context.Context, id int, options *UpdateAttachmentOptions) (Attachment, error) {
+	if err := options.Validate(); err != nil {
+		return Attachment{}, err
+	}
+	resp, err := c.request(ctx, "UpdateAttachment", fmt.Sprintf("/attachments/%d", id), "PUT", options.values())
 	if err != nil {
 		return Attachment{}, err
 	}
-	if status != http.StatusOK {
-		return Attachment{}, handleError(b)
+	if resp.statusCode != http.StatusOK {
+		return Attachment{}, resp.err()
 	}
 	var m Attachment
-	err = json.Unmarshal(b, &m)
+	err = json.Unmarshal(resp.body, &m)
 	return m, err
 }
 
 // DeleteAttachment removes the specified attachment from the parent message. If this is the only
 // attachment in the message then the parent message will also be removed.
 func (c *Client) DeleteAttachment(id int) error {
-	b, status, err := c.request(fmt.Sprintf("/attachments/%d", id), "DELETE", url.Values{})
+	return c.DeleteAttachmentContext(context.Background(), id)
+}
+
+// DeleteAttachmentContext is like DeleteAttachment, but takes a context that controls
+// cancellation and deadlines of the underlying request.
+func (c *Client) DeleteAttachmentContext(ctx context.Context, id int) error {
+	resp, err := c.request(ctx, "DeleteAttachment", fmt.Sprintf("/attachments/%d", id), "DELETE", url.Values{})
 	if err != nil {
 		return err
 	}
-	if status != http.StatusNoContent {
-		return handleError(b)
+	if resp.statusCode != http.StatusNoContent {
+		return resp.err()
 	}
 	return nil
 }
@@ -1,7 +1,9 @@
 package sqwiggle_test
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/hermanschaaf/sqwiggle"
 )
@@ -85,3 +87,37 @@ func ExampleClient_DeleteMessage() {
 		panic(err)
 	}
 }
+
+// The following code instantiates a client, then calls the
+// ListMessagesContext method with a context that gives up after ten
+// seconds, so that a stalled API cannot block the caller forever.
+func ExampleClient_ListMessagesContext() {
+	client := sqwiggle.NewClient("YOUR-API-KEY")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	msgs, err := client.ListMessagesContext(ctx, 0, 50)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, m := range msgs {
+		fmt.Printf("%s: %s\n", m.Author.Name, m.Text)
+	}
+}
+
+// The following code instantiates a client, then uses the AllMessages
+// iterator to print the 200 most recent messages, fetching further pages
+// only as they are needed.
+func ExampleClient_AllMessages() {
+	client := sqwiggle.NewClient("YOUR-API-KEY")
+
+	opts := &sqwiggle.PageOptions{MaxItems: 200}
+	for m, err := range client.AllMessages(context.Background(), opts) {
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s: %s\n", m.Author.Name, m.Text)
+	}
+}
@@ -1,6 +1,9 @@
 package sqwiggle
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -168,6 +171,132 @@ func Test_ListMessages_Success(t *testing.T) {
 
 }
 
+// Test_ListStreamMessages_Success instantiates a new Client and calls the
+// ListStreamMessages method, which uses the nested stream endpoint.
+func Test_ListStreamMessages_Success(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/listmessages.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantData := map[string]string{
+		"page":  "2",
+		"limit": "3",
+	}
+
+	server, client := setupTestServer(200, dummy, want(t, "/streams/48914/messages", "GET", wantData))
+	defer server.Close()
+
+	msgs, err := client.ListStreamMessages(48914, 2, 3)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("len(msgs) = %d, want %d", len(msgs), 3)
+	}
+}
+
+// Test_ListMessagesWithOptions_Fallback checks that messages are filtered
+// by the client when the API has no nested stream endpoint.
+func Test_ListMessagesWithOptions_Fallback(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/messages":
+		case "/streams/1":
+			w.Write([]byte(`{"id": 1, "name": "General"}`))
+			return
+		case "/streams/2/messages":
+			// the API knows the route, but not the stream
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type": "unknown", "message": "Not found"}`))
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("404 page not found"))
+			return
+		}
+		w.Write([]byte(`[
+			{"id": 6, "stream_id": 1, "author": {"id": 10}, "created_at": "2015-02-05T13:00:00Z"},
+			{"id": 5, "stream_id": 2, "author": {"id": 10}, "created_at": "2015-02-05T12:00:00Z"},
+			{"id": 4, "stream_id": 1, "author": {"id": 11}, "created_at": "2015-02-05T11:00:00Z", "conversation_id": 7},
+			{"id": 3, "stream_id": 1, "author": {"id": 10}, "created_at": "2015-02-05T10:00:00Z"}
+		]`))
+	}))
+	defer server.Close()
+	client := NewClient("test")
+	client.RootURL = server.URL
+
+	tests := []struct {
+		options *ListMessagesOptions
+		want    []int
+	}{
+		{&ListMessagesOptions{StreamID: 1}, []int{6, 4, 3}},
+		{&ListMessagesOptions{StreamID: 1, AuthorID: 10}, []int{6, 3}},
+		{&ListMessagesOptions{ConversationID: 7}, []int{4}},
+		{&ListMessagesOptions{Since: time.Date(2015, time.February, 5, 11, 0, 0, 0, time.UTC)}, []int{6, 5, 4}},
+		{&ListMessagesOptions{Until: time.Date(2015, time.February, 5, 11, 0, 0, 0, time.UTC)}, []int{3}},
+		{&ListMessagesOptions{BeforeID: 6, AfterID: 3}, []int{5, 4}},
+		// ranges that can only be empty are valid
+		{&ListMessagesOptions{BeforeID: 5, AfterID: 4}, []int{}},
+		{&ListMessagesOptions{Since: time.Date(2015, time.February, 5, 11, 30, 0, 0, time.UTC), Until: time.Date(2015, time.February, 5, 11, 30, 0, 0, time.UTC)}, []int{}},
+		{nil, []int{6, 5, 4, 3}},
+	}
+	for _, tt := range tests {
+		paths = nil
+		msgs, err := client.ListMessagesWithOptions(0, 0, tt.options)
+		if err != nil {
+			t.Fatalf("ListMessagesWithOptions(%+v) returned error: %v", tt.options, err)
+		}
+		got := []int{}
+		for _, m := range msgs {
+			got = append(got, m.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListMessagesWithOptions(%+v) = %v, want %v", tt.options, got, tt.want)
+		}
+		if tt.options != nil && tt.options.StreamID != 0 && len(paths) != 3 {
+			t.Errorf("requested %v, want the stream endpoint, the stream and then /messages", paths)
+		}
+	}
+
+	// the requests of the fallback are made on behalf of the caller
+	var ops []string
+	client.Middleware = []Middleware{func(next Handler) Handler {
+		return func(op string, req *http.Request) (*http.Response, error) {
+			ops = append(ops, op)
+			return next(op, req)
+		}
+	}}
+	if _, err := client.ListStreamMessages(1, 0, 0); err != nil {
+		t.Fatal("got error:", err)
+	}
+	if want := []string{"ListStreamMessages", "ListStreamMessages", "ListStreamMessages"}; !reflect.DeepEqual(ops, want) {
+		t.Errorf("operations = %v, want %v", ops, want)
+	}
+	client.Middleware = nil
+
+	// a stream that does not exist is an error, not an empty list
+	for _, streamID := range []int{2, 3} {
+		msgs, err := client.ListMessagesWithOptions(0, 0, &ListMessagesOptions{StreamID: streamID})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+			t.Errorf("ListMessagesWithOptions of missing stream %d = %v, %v, want a 404 error", streamID, msgs, err)
+		}
+	}
+
+	for _, options := range []*ListMessagesOptions{
+		{BeforeID: 3, AfterID: 3},
+		{Since: time.Date(2015, time.February, 5, 12, 0, 0, 0, time.UTC), Until: time.Date(2015, time.February, 5, 11, 0, 0, 0, time.UTC)},
+	} {
+		_, err := client.ListMessagesWithOptions(0, 0, options)
+		if !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("ListMessagesWithOptions(%+v) returned %v, want %q", options, err, ErrInvalidParam)
+		}
+	}
+}
+
 // Test_GetMessage_Success instantiates a new Client and calls the GetMessage method
 // to return a single message.
 func Test_GetMessages_Success(t *testing.T) {
@@ -359,8 +488,19 @@ func Test_Messages_Failure(t *testing.T) {
 	}
 	for i := range funcs {
 		err := funcs[i]()
-		if !reflect.DeepEqual(err, wantErr) {
-			t.Errorf("err = %v, want %+v (Error struct)", err, wantErr)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("err = %v, want *APIError", err)
+			continue
+		}
+		if !reflect.DeepEqual(apiErr.Err, wantErr) {
+			t.Errorf("err = %v, want %+v (Error struct)", apiErr.Err, wantErr)
+		}
+		if apiErr.StatusCode != 400 {
+			t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, 400)
+		}
+		if !errors.Is(err, ErrAuthentication) {
+			t.Errorf("errors.Is(%v, ErrAuthentication) = false, want true", err)
 		}
 	}
 }
@@ -598,11 +738,10 @@ func Test_UpdateUser_Success(t *testing.T) {
 	server, client := setupTestServer(200, dummy, want(t, "/users/3434978", "PUT", wantData))
 	defer server.Close()
 
-	values := url.Values{
-		"name":  []string{"amazing"},
-		"email": []string{"yo@yo.com"},
-	}
-	m, err := client.UpdateUser(3434978, values)
+	m, err := client.UpdateUser(3434978, &UpdateUserOptions{
+		Name:  String("amazing"),
+		Email: String("yo@yo.com"),
+	})
 	if err != nil {
 		t.Fatal("got error:", err)
 	}
@@ -610,6 +749,36 @@ func Test_UpdateUser_Success(t *testing.T) {
 	validateUser(t, m)
 }
 
+// Test_UpdateUser_Invalid checks that invalid options are rejected without
+// making a request.
+func Test_UpdateUser_Invalid(t *testing.T) {
+	server, client := setupTestServer(200, nil, func(r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+	defer server.Close()
+
+	tests := []struct {
+		options *UpdateUserOptions
+		param   string
+	}{
+		{&UpdateUserOptions{Name: String("")}, "name"},
+		{&UpdateUserOptions{Email: String("nope")}, "email"},
+		{&UpdateUserOptions{Status: Status("asleep")}, "status"},
+		{&UpdateUserOptions{SnapshotInterval: Int(30)}, "snapshot_interval"},
+	}
+	for _, tt := range tests {
+		_, err := client.UpdateUser(3434978, tt.options)
+		var e Error
+		if !errors.Is(err, ErrInvalidParam) || !errors.As(err, &e) || e.Param != tt.param {
+			t.Errorf("UpdateUser(%+v): err = %v, want invalid %s", tt.options, err, tt.param)
+		}
+	}
+
+	if err := (&UpdateUserOptions{SnapshotInterval: Int(0)}).Validate(); err != nil {
+		t.Errorf("Validate() with a snapshot interval of 0 returned %v", err)
+	}
+}
+
 /*************************************************************************
 
   Organizations
@@ -706,19 +875,14 @@ func Test_UpdateOrganization_Success(t *testing.T) {
 	}
 
 	wantData := map[string]string{
-		"name":  "amazing",
-		"email": "yo@yo.com",
+		"name": "amazing",
 	}
 
 	// set up server to return 200 and message
 	server, client := setupTestServer(200, dummy, want(t, "/organizations/3434978", "PUT", wantData))
 	defer server.Close()
 
-	values := url.Values{
-		"name":  []string{"amazing"},
-		"email": []string{"yo@yo.com"},
-	}
-	m, err := client.UpdateOrganization(3434978, values)
+	m, err := client.UpdateOrganization(3434978, &UpdateOrganizationOptions{Name: String("amazing")})
 	if err != nil {
 		t.Fatal("got error:", err)
 	}
@@ -750,6 +914,71 @@ func Test_GetInfo_Success(t *testing.T) {
 	}
 }
 
+// Test_GetInfoTyped_Success instantiates a new Client and calls the GetInfoTyped method
+// to return the current info as an Info struct.
+func Test_GetInfoTyped_Success(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/info.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := setupTestServer(200, dummy, want(t, "/info", "GET", nil))
+	defer server.Close()
+
+	info, err := client.GetInfoTyped()
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+
+	cfg := info.Configuration
+	if cfg.Storage.Uploads != "sqwiggle-user-uploads" {
+		t.Errorf("Storage.Uploads = %q, want %q", cfg.Storage.Uploads, "sqwiggle-user-uploads")
+	}
+	if len(cfg.Commands) != 14 || cfg.Commands["/dnd"] != `Changes to busy with a message of "Do Not Disturb"` {
+		t.Errorf("Commands = %v, want 14 commands including /dnd", cfg.Commands)
+	}
+	if cfg.MaxUploadFilesize != 26214400 || cfg.MaxConversationParticipants != 10 || cfg.MaxFreeStreams != 5 {
+		t.Errorf("limits = %d, %d, %d, want 26214400, 10, 5", cfg.MaxUploadFilesize, cfg.MaxConversationParticipants, cfg.MaxFreeStreams)
+	}
+	wantICE := ICEServer{
+		URL:        "turn:turn.sqwiggle.com:3478?transport=tcp",
+		Username:   "sqwiggle",
+		Credential: "5f4dcc3b5aa765d61d8327deb882cf99",
+	}
+	if len(cfg.ICEServers) != 3 || cfg.ICEServers[2] != wantICE {
+		t.Errorf("ICEServers = %+v, want 3 servers ending with %+v", cfg.ICEServers, wantICE)
+	}
+	wantExpiry := time.Date(2015, time.February, 8, 8, 4, 35, 0, time.UTC)
+	if !cfg.ICEServersExpireAt.Equal(wantExpiry) {
+		t.Errorf("ICEServersExpireAt = %v, want %v", cfg.ICEServersExpireAt, wantExpiry)
+	}
+	if r := info.Releases["mac"]; r.Current != "0.6.5" || r.Minimum != "0.6.5" {
+		t.Errorf("Releases[mac] = %+v, want 0.6.5 / 0.6.5", r)
+	}
+	if info.Extra != nil || cfg.Extra != nil {
+		t.Errorf("Extra = %v, %v, want none", info.Extra, cfg.Extra)
+	}
+}
+
+// TestInfo_Extra checks that unknown keys in the /info response are kept.
+func TestInfo_Extra(t *testing.T) {
+	b := []byte(`{"configuration": {"max_free_streams": 3, "emoji": [":cat2:"]}, "motd": "hi"}`)
+
+	var info Info
+	if err := json.Unmarshal(b, &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Configuration.MaxFreeStreams != 3 {
+		t.Errorf("MaxFreeStreams = %d, want %d", info.Configuration.MaxFreeStreams, 3)
+	}
+	if string(info.Extra["motd"]) != `"hi"` {
+		t.Errorf("Extra[motd] = %s, want %q", info.Extra["motd"], "hi")
+	}
+	if string(info.Configuration.Extra["emoji"]) != `[":cat2:"]` {
+		t.Errorf("Configuration.Extra[emoji] = %s, want %s", info.Configuration.Extra["emoji"], `[":cat2:"]`)
+	}
+}
+
 /*************************************************************************
 
   Conversations
@@ -1033,7 +1262,7 @@ func Test_UpdateAttachment_Success(t *testing.T) {
 	server, client := setupTestServer(200, dummy, want(t, "/attachments/3434978", "PUT", wantData))
 	defer server.Close()
 
-	m, err := client.UpdateAttachment(3434978, url.Values{"title": []string{"amazing"}, "description": []string{"so good"}})
+	m, err := client.UpdateAttachment(3434978, &UpdateAttachmentOptions{Title: String("amazing"), Description: String("so good")})
 	if err != nil {
 		t.Fatal("got error:", err)
 	}
@@ -1041,6 +1270,20 @@ func Test_UpdateAttachment_Success(t *testing.T) {
 	validateAttachment(t, m)
 }
 
+// Test_UpdateAttachment_Invalid checks that an unknown status is rejected
+// without making a request.
+func Test_UpdateAttachment_Invalid(t *testing.T) {
+	server, client := setupTestServer(200, nil, func(r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+	defer server.Close()
+
+	_, err := client.UpdateAttachment(3434978, &UpdateAttachmentOptions{Status: String("done")})
+	if !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("err = %v, want %q", err, ErrInvalidParam)
+	}
+}
+
 // Test_DeleteAttachment_Success instantiates a new Client and calls the DeleteAttachment method.
 func Test_DeleteAttachment_Success(t *testing.T) {
 	// set up server to return 204 and message
@@ -1052,3 +1295,54 @@ func Test_DeleteAttachment_Success(t *testing.T) {
 		t.Fatal("got error:", err)
 	}
 }
+
+/*************************************************************************
+
+  Context
+
+*************************************************************************/
+
+// Test_Context_Deadline checks that a request is aborted once the
+// deadline of the context passed to a ...Context method expires.
+func Test_Context_Deadline(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	// set up server that never responds until the test finishes
+	server, client := setupTestServer(200, []byte("[]"), func(r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.ListMessagesContext(ctx, 0, 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// Test_Context_Cancelled checks that no request is made with a context
+// that was cancelled beforehand.
+func Test_Context_Cancelled(t *testing.T) {
+	called := false
+	server, client := setupTestServer(204, []byte{}, func(r *http.Request) {
+		called = true
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.DeleteMessageContext(ctx, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+	if called {
+		t.Error("request reached the server, want it aborted")
+	}
+}
@@ -0,0 +1,456 @@
+package sqwiggletest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hermanschaaf/sqwiggle"
+)
+
+// parseMentions replaces mentions in text by the names of the mentioned
+// users, and returns the resulting text along with the mentions, whose
+// indices point into the returned text.
+func (s *Server) parseMentions(messageID int, text string) (string, []sqwiggle.Mention) {
+	out, mentions := sqwiggle.RenderSegments(sqwiggle.ParseMentions(text))
+	for i := range mentions {
+		mentions[i].ID = s.id()
+		mentions[i].MessageID = messageID
+	}
+	return out, mentions
+}
+
+// author returns the user that the API key belongs to, as shown in the
+// author field of messages.
+func (s *Server) author() sqwiggle.User {
+	u := s.users[s.self]
+	return sqwiggle.User{ID: u.ID, Name: u.Name, Avatar: u.Avatar, Type: u.Type}
+}
+
+// removeMessage replaces a message by the note left in its place.
+func (s *Server) removeMessage(m *sqwiggle.Message) {
+	for _, a := range m.Attachments {
+		delete(s.attachments, a.ID)
+	}
+	m.Text = sqwiggle.RemovedText
+	m.Attachments = []sqwiggle.Attachment{}
+	m.Mentions = []sqwiggle.Mention{}
+	m.UpdatedAt = s.now()
+}
+
+// sortedMessages returns the messages for which keep returns true, newest
+// first.
+func (s *Server) sortedMessages(keep func(m *sqwiggle.Message) bool) []sqwiggle.Message {
+	msgs := values(s.messages, func(a, b *sqwiggle.Message) bool {
+		if a.CreatedAt.Equal(b.CreatedAt) {
+			return a.ID > b.ID
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+	kept := msgs[:0]
+	for i := range msgs {
+		if keep(&msgs[i]) {
+			kept = append(kept, msgs[i])
+		}
+	}
+	return kept
+}
+
+func (s *Server) serveMessages(w http.ResponseWriter, r *http.Request, id int) {
+	if id == 0 {
+		switch r.Method {
+		case "GET":
+			msgs := s.sortedMessages(func(m *sqwiggle.Message) bool { return true })
+			writeJSON(w, http.StatusOK, paginate(r, msgs))
+		case "POST":
+			if !checkParams(w, r, "stream_id", "text", "format", "parse") {
+				return
+			}
+			streamID, _ := strconv.Atoi(r.PostForm.Get("stream_id"))
+			if _, ok := s.streams[streamID]; !ok {
+				invalidParam(w, "stream_id", "A valid stream_id must be provided")
+				return
+			}
+			if r.PostForm.Get("text") == "" {
+				invalidParam(w, "text", "Text must be provided")
+				return
+			}
+			m := sqwiggle.Message{
+				ID:          s.id(),
+				StreamID:    streamID,
+				Author:      s.author(),
+				Attachments: []sqwiggle.Attachment{},
+				CreatedAt:   s.now(),
+			}
+			m.Text, m.Mentions = s.parseMentions(m.ID, r.PostForm.Get("text"))
+			m.UpdatedAt = m.CreatedAt
+			s.messages[m.ID] = &m
+			writeJSON(w, http.StatusCreated, m)
+		default:
+			methodNotAllowed(w)
+		}
+		return
+	}
+
+	m, ok := s.messages[id]
+	if !ok {
+		notFound(w)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, m)
+	case "PUT":
+		if !checkParams(w, r, "text") {
+			return
+		}
+		if text, ok := r.PostForm["text"]; ok {
+			m.Text, m.Mentions = s.parseMentions(m.ID, text[0])
+		}
+		m.UpdatedAt = s.now()
+		writeJSON(w, http.StatusOK, m)
+	case "DELETE":
+		s.removeMessage(m)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveStreams(w http.ResponseWriter, r *http.Request, id int) {
+	if id == 0 {
+		switch r.Method {
+		case "GET":
+			streams := values(s.streams, func(a, b *sqwiggle.Stream) bool {
+				return a.Name < b.Name
+			})
+			writeJSON(w, http.StatusOK, paginate(r, streams))
+		case "POST":
+			if !checkParams(w, r, "name") {
+				return
+			}
+			name := r.PostForm.Get("name")
+			if name == "" {
+				invalidParam(w, "name", "Name must be provided")
+				return
+			}
+			st := sqwiggle.Stream{
+				ID:         s.id(),
+				UserID:     s.self,
+				Name:       name,
+				Path:       pathFor(name),
+				Subscribed: true,
+				CreatedAt:  s.now(),
+				Status:     sqwiggle.StreamStatusActive,
+				Type:       sqwiggle.StreamTypeStandard,
+			}
+			s.streams[st.ID] = &st
+			writeJSON(w, http.StatusCreated, st)
+		default:
+			methodNotAllowed(w)
+		}
+		return
+	}
+
+	st, ok := s.streams[id]
+	if !ok {
+		notFound(w)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, st)
+	case "PUT":
+		if !checkParams(w, r, "name") {
+			return
+		}
+		if name := r.PostForm.Get("name"); name != "" {
+			st.Name = name
+			st.Path = pathFor(name)
+		}
+		writeJSON(w, http.StatusOK, st)
+	case "DELETE":
+		delete(s.streams, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// serveStreamMessages serves GET /streams/:id/messages.
+func (s *Server) serveStreamMessages(w http.ResponseWriter, r *http.Request, id int) {
+	if _, ok := s.streams[id]; !ok {
+		notFound(w)
+		return
+	}
+	if r.Method != "GET" {
+		methodNotAllowed(w)
+		return
+	}
+	msgs := s.sortedMessages(func(m *sqwiggle.Message) bool { return m.StreamID == id })
+	writeJSON(w, http.StatusOK, paginate(r, msgs))
+}
+
+func (s *Server) serveUsers(w http.ResponseWriter, r *http.Request, id int) {
+	if id == 0 {
+		if r.Method != "GET" {
+			methodNotAllowed(w)
+			return
+		}
+		users := values(s.users, func(a, b *sqwiggle.User) bool {
+			return a.ID < b.ID
+		})
+		writeJSON(w, http.StatusOK, paginate(r, users))
+		return
+	}
+
+	u, ok := s.users[id]
+	if !ok {
+		notFound(w)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, u)
+	case "PUT":
+		if !checkParams(w, r, "name", "email", "time_zone", "avatar", "status", "message", "snapshot", "snapshot_interval") {
+			return
+		}
+		updated := *u
+		f := r.PostForm
+		if v, ok := f["status"]; ok {
+			switch sqwiggle.UserStatus(v[0]) {
+			case sqwiggle.StatusBusy, sqwiggle.StatusAvailable, sqwiggle.StatusOffline:
+				updated.Status = sqwiggle.UserStatus(v[0])
+			default:
+				invalidParam(w, "status", "Status must be one of busy, available or offline")
+				return
+			}
+		}
+		if v, ok := f["snapshot_interval"]; ok {
+			n, err := strconv.Atoi(v[0])
+			if err != nil || (n != 0 && n < 60) {
+				invalidParam(w, "snapshot_interval", "Snapshot interval must be 0 or greater than 59")
+				return
+			}
+			updated.SnapshotInterval = n
+		}
+		for k, field := range map[string]*string{
+			"name":      &updated.Name,
+			"email":     &updated.Email,
+			"time_zone": &updated.TimeZone,
+			"avatar":    &updated.Avatar,
+			"message":   &updated.Message,
+			"snapshot":  &updated.Snapshot,
+		} {
+			if v, ok := f[k]; ok {
+				*field = v[0]
+			}
+		}
+		*u = updated
+		writeJSON(w, http.StatusOK, u)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveInvites(w http.ResponseWriter, r *http.Request, id int) {
+	if id == 0 {
+		switch r.Method {
+		case "GET":
+			invites := values(s.invites, func(a, b *sqwiggle.Invite) bool {
+				return a.ID < b.ID
+			})
+			writeJSON(w, http.StatusOK, paginate(r, invites))
+		case "POST":
+			if !checkParams(w, r, "email") {
+				return
+			}
+			email := r.PostForm.Get("email")
+			if email == "" {
+				invalidParam(w, "email", "Email must be provided")
+				return
+			}
+			i := sqwiggle.Invite{
+				ID:        s.id(),
+				FromID:    s.self,
+				Email:     email,
+				CreatedAt: s.now(),
+			}
+			i.URL = fmt.Sprintf("%s/signup/%d", s.URL, i.ID)
+			s.invites[i.ID] = &i
+			writeJSON(w, http.StatusCreated, i)
+		default:
+			methodNotAllowed(w)
+		}
+		return
+	}
+
+	i, ok := s.invites[id]
+	if !ok {
+		notFound(w)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, i)
+	case "DELETE":
+		delete(s.invites, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveAttachments(w http.ResponseWriter, r *http.Request, id int) {
+	if id == 0 {
+		switch r.Method {
+		case "GET":
+			attachments := values(s.attachments, func(a, b *sqwiggle.Attachment) bool {
+				if a.CreatedAt.Equal(b.CreatedAt) {
+					return a.ID > b.ID
+				}
+				return a.CreatedAt.After(b.CreatedAt)
+			})
+			writeJSON(w, http.StatusOK, paginate(r, attachments))
+		case "POST":
+			if !checkParams(w, r, "name") {
+				return
+			}
+			name := r.PostForm.Get("name")
+			if name == "" {
+				invalidParam(w, "name", "Name must be provided")
+				return
+			}
+			a := sqwiggle.Attachment{
+				ID:        s.id(),
+				Type:      sqwiggle.TypeFile,
+				Title:     name,
+				Status:    "pending",
+				CreatedAt: s.now(),
+			}
+			a.UpdatedAt = a.CreatedAt
+			s.attachments[a.ID] = &a
+			writeJSON(w, http.StatusCreated, a)
+		default:
+			methodNotAllowed(w)
+		}
+		return
+	}
+
+	a, ok := s.attachments[id]
+	if !ok {
+		notFound(w)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, a)
+	case "PUT":
+		if !checkParams(w, r, "title", "description", "url", "image", "status") {
+			return
+		}
+		f := r.PostForm
+		if v, ok := f["status"]; ok && v[0] != "pending" && v[0] != "uploaded" {
+			invalidParam(w, "status", "Status must be one of pending or uploaded")
+			return
+		}
+		for k, field := range map[string]*string{
+			"title":       &a.Title,
+			"description": &a.Description,
+			"url":         &a.URL,
+			"image":       &a.Image,
+			"status":      &a.Status,
+		} {
+			if v, ok := f[k]; ok {
+				*field = v[0]
+			}
+		}
+		a.UpdatedAt = s.now()
+		s.updateMessageAttachments(a.ID, func(ma *sqwiggle.Attachment) { *ma = *a })
+		writeJSON(w, http.StatusOK, a)
+	case "DELETE":
+		delete(s.attachments, id)
+		s.updateMessageAttachments(id, nil)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// updateMessageAttachments applies update to the copies of attachment id
+// held by messages. If update is nil, the attachment is removed from the
+// messages instead, and messages left without attachments are removed.
+func (s *Server) updateMessageAttachments(id int, update func(*sqwiggle.Attachment)) {
+	for _, m := range s.messages {
+		for i := range m.Attachments {
+			if m.Attachments[i].ID != id {
+				continue
+			}
+			if update != nil {
+				update(&m.Attachments[i])
+				break
+			}
+			m.Attachments = append(m.Attachments[:i], m.Attachments[i+1:]...)
+			if len(m.Attachments) == 0 {
+				s.removeMessage(m)
+			}
+			break
+		}
+	}
+}
+
+func (s *Server) serveConversations(w http.ResponseWriter, r *http.Request, id int) {
+	if r.Method != "GET" {
+		methodNotAllowed(w)
+		return
+	}
+	if id == 0 {
+		conversations := values(s.conversations, func(a, b *sqwiggle.Conversation) bool {
+			return a.CreatedAt.After(b.CreatedAt)
+		})
+		writeJSON(w, http.StatusOK, paginate(r, conversations))
+		return
+	}
+	c, ok := s.conversations[id]
+	if !ok {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (s *Server) serveOrganizations(w http.ResponseWriter, r *http.Request, id int) {
+	if id == 0 {
+		if r.Method != "GET" {
+			methodNotAllowed(w)
+			return
+		}
+		orgs := values(s.organizations, func(a, b *sqwiggle.Organization) bool {
+			return a.ID < b.ID
+		})
+		writeJSON(w, http.StatusOK, paginate(r, orgs))
+		return
+	}
+
+	o, ok := s.organizations[id]
+	if !ok {
+		notFound(w)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, o)
+	case "PUT":
+		if !checkParams(w, r, "name") {
+			return
+		}
+		if name := r.PostForm.Get("name"); name != "" {
+			o.Name = name
+			o.Path = pathFor(name)
+		}
+		writeJSON(w, http.StatusOK, o)
+	default:
+		methodNotAllowed(w)
+	}
+}
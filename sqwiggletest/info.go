@@ -0,0 +1,58 @@
+package sqwiggletest
+
+// defaultInfo is the response to GET /info until it is replaced with
+// Server.SetInfo. It mirrors the structure of the real response, with
+// made up ICE server credentials.
+const defaultInfo = `{
+  "configuration": {
+    "storage": {
+      "avatars": "sqwiggle-photos",
+      "clients": "sqwiggle-clients",
+      "uploads": "sqwiggle-user-uploads"
+    },
+    "commands": {
+      "/available": "Change to available, anything after the command becomes your status",
+      "/brb": "Change to busy with a message of \"Be Right Back\"",
+      "/busy": "Change to busy, anything after the command becomes your status",
+      "/dnd": "Changes to busy with a message of \"Do Not Disturb\"",
+      "/gif": "Inserts a gif, type anything after the command to search",
+      "/invite": "Sends an invite to the email address you type",
+      "/leave": "Leaves the current conversation",
+      "/me": "Creates an action in the stream, \"eg\": /me waves",
+      "/mute": "Mutes your audio when in a conversation",
+      "/nick": "Changes your profile name",
+      "/ping": "Pings the user you @mention",
+      "/status": "Add a status message to let everyone know what you're up to",
+      "/unbusy": "Removes busy status and message, setting you as available",
+      "/unmute": "Unmutes your audio when in a conversation"
+    },
+    "max_upload_filesize": 26214400,
+    "max_conversation_participants": 10,
+    "max_free_streams": 5,
+    "iceservers": [
+      {
+        "url": "stun:stun.example.com:3478?transport=udp"
+      },
+      {
+        "url": "turn:turn.example.com:3478?transport=tcp",
+        "username": "test",
+        "credential": "test-credential"
+      }
+    ],
+    "iceservers_expire_at": "2030-01-01T00:00:00.000+00:00"
+  },
+  "releases": {
+    "windows": {
+      "current": "0.0.0",
+      "minimum": "0.0.0"
+    },
+    "ios": {
+      "current": "0.1.2",
+      "minimum": "0.1.0"
+    },
+    "mac": {
+      "current": "0.6.5",
+      "minimum": "0.6.5"
+    }
+  }
+}`
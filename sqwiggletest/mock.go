@@ -0,0 +1,656 @@
+package sqwiggletest
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+
+	"github.com/hermanschaaf/sqwiggle"
+)
+
+// Mock is an implementation of sqwiggle.API for unit tests. The behaviour
+// of each operation is defined by the function field named after it, such
+// as ListMessagesFunc; operations whose field is nil fail with an error.
+// The methods without a Context suffix call their Context variant with
+// context.Background(), and the All* iterators page through the
+// corresponding List*Func, so only the Context variants need to be set.
+//
+// Every call is recorded, and can be inspected with Calls. Mock is safe
+// for concurrent use, provided the function fields are not changed while
+// it is in use.
+type Mock struct {
+	ListMessagesFunc            func(ctx context.Context, page, limit int) ([]sqwiggle.Message, error)
+	ListMessagesWithOptionsFunc func(ctx context.Context, page, limit int, options *sqwiggle.ListMessagesOptions) ([]sqwiggle.Message, error)
+	ListStreamMessagesFunc      func(ctx context.Context, streamID, page, limit int) ([]sqwiggle.Message, error)
+	GetMessageFunc              func(ctx context.Context, id int) (sqwiggle.Message, error)
+	PostMessageFunc             func(ctx context.Context, streamID int, text string, options *sqwiggle.PostMessageOptions) (sqwiggle.Message, error)
+	UpdateMessageFunc           func(ctx context.Context, id int, text string) (sqwiggle.Message, error)
+	DeleteMessageFunc           func(ctx context.Context, id int) error
+	ListStreamsFunc             func(ctx context.Context, page, limit int) ([]sqwiggle.Stream, error)
+	GetStreamFunc               func(ctx context.Context, id int) (sqwiggle.Stream, error)
+	PostStreamFunc              func(ctx context.Context, name string) (sqwiggle.Stream, error)
+	UpdateStreamFunc            func(ctx context.Context, id int, name string) (sqwiggle.Stream, error)
+	DeleteStreamFunc            func(ctx context.Context, id int) error
+	ListUsersFunc               func(ctx context.Context, page, limit int) ([]sqwiggle.User, error)
+	GetUserFunc                 func(ctx context.Context, id int) (sqwiggle.User, error)
+	UpdateUserFunc              func(ctx context.Context, id int, options *sqwiggle.UpdateUserOptions) (sqwiggle.User, error)
+	ListOrganizationsFunc       func(ctx context.Context, page, limit int) ([]sqwiggle.Organization, error)
+	GetOrganizationFunc         func(ctx context.Context, id int) (sqwiggle.Organization, error)
+	UpdateOrganizationFunc      func(ctx context.Context, id int, options *sqwiggle.UpdateOrganizationOptions) (sqwiggle.Organization, error)
+	GetInfoFunc                 func(ctx context.Context) ([]byte, error)
+	GetInfoTypedFunc            func(ctx context.Context) (sqwiggle.Info, error)
+	ListConversationsFunc       func(ctx context.Context, page, limit int) ([]sqwiggle.Conversation, error)
+	GetConversationFunc         func(ctx context.Context, id int) (sqwiggle.Conversation, error)
+	ListInvitesFunc             func(ctx context.Context, page, limit int) ([]sqwiggle.Invite, error)
+	GetInviteFunc               func(ctx context.Context, id int) (sqwiggle.Invite, error)
+	PostInviteFunc              func(ctx context.Context, email string) (sqwiggle.Invite, error)
+	DeleteInviteFunc            func(ctx context.Context, id int) error
+	ListAttachmentsFunc         func(ctx context.Context, page, limit int) ([]sqwiggle.Attachment, error)
+	GetAttachmentFunc           func(ctx context.Context, id int) (sqwiggle.Attachment, error)
+	PostAttachmentFunc          func(ctx context.Context, name string) (sqwiggle.Attachment, error)
+	UpdateAttachmentFunc        func(ctx context.Context, id int, options *sqwiggle.UpdateAttachmentOptions) (sqwiggle.Attachment, error)
+	DeleteAttachmentFunc        func(ctx context.Context, id int) error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Mock implements sqwiggle.API.
+var _ sqwiggle.API = (*Mock)(nil)
+
+// Call is a recorded call to a Mock.
+type Call struct {
+	Method string        // Name of the operation, without Context suffix
+	Args   []interface{} // Arguments of the call, excluding the context
+}
+
+// Calls returns all calls made to the Mock so far, in order. If method is
+// not empty, only calls to that operation are returned.
+func (m *Mock) Calls(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var calls []Call
+	for _, c := range m.calls {
+		if method == "" || c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// record records a call to method.
+func (m *Mock) record(method string, args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, Call{Method: method, Args: args})
+}
+
+// notImplemented returns the error for calls to operations without a
+// function set.
+func notImplemented(method string) error {
+	return fmt.Errorf("sqwiggletest: Mock.%sFunc is not set", method)
+}
+
+/*************************************************************************
+
+  Messages
+
+*************************************************************************/
+
+// ListMessages is an implementation of the sqwiggle.API interface
+func (m *Mock) ListMessages(page, limit int) ([]sqwiggle.Message, error) {
+	return m.ListMessagesContext(context.Background(), page, limit)
+}
+
+// ListMessagesContext is an implementation of the sqwiggle.API interface
+func (m *Mock) ListMessagesContext(ctx context.Context, page, limit int) ([]sqwiggle.Message, error) {
+	m.record("ListMessages", page, limit)
+	if m.ListMessagesFunc == nil {
+		return nil, notImplemented("ListMessages")
+	}
+	return m.ListMessagesFunc(ctx, page, limit)
+}
+
+// AllMessages is an implementation of the sqwiggle.API interface
+func (m *Mock) AllMessages(ctx context.Context, opts *sqwiggle.PageOptions) iter.Seq2[sqwiggle.Message, error] {
+	return sqwiggle.Paginate(ctx, opts, m.ListMessagesContext)
+}
+
+// ListMessagesWithOptions is an implementation of the sqwiggle.API interface
+func (m *Mock) ListMessagesWithOptions(page, limit int, options *sqwiggle.ListMessagesOptions) ([]sqwiggle.Message, error) {
+	return m.ListMessagesWithOptionsContext(context.Background(), page, limit, options)
+}
+
+// ListMessagesWithOptionsContext is an implementation of the sqwiggle.API interface
+func (m *Mock) ListMessagesWithOptionsContext(ctx context.Context, page, limit int, options *sqwiggle.ListMessagesOptions) ([]sqwiggle.Message, error) {
+	m.record("ListMessagesWithOptions", page, limit, options)
+	if m.ListMessagesWithOptionsFunc == nil {
+		return nil, notImplemented("ListMessagesWithOptions")
+	}
+	return m.ListMessagesWithOptionsFunc(ctx, page, limit, options)
+}
+
+// AllMessagesWithOptions is an implementation of the sqwiggle.API
+// interface. Like the Client, it pages through ListStreamMessages, or
+// ListMessages if options.StreamID is not set, and filters the messages
+// itself, so that short filtered pages do not end the iteration.
+func (m *Mock) AllMessagesWithOptions(ctx context.Context, opts *sqwiggle.PageOptions, options *sqwiggle.ListMessagesOptions) iter.Seq2[sqwiggle.Message, error] {
+	var o sqwiggle.PageOptions
+	if opts != nil {
+		o = *opts
+	}
+	max := o.MaxItems
+	o.MaxItems = 0
+	list := m.ListMessagesContext
+	if options != nil && options.StreamID != 0 {
+		list = func(ctx context.Context, page, limit int) ([]sqwiggle.Message, error) {
+			return m.ListStreamMessagesContext(ctx, options.StreamID, page, limit)
+		}
+	}
+
+	return func(yield func(sqwiggle.Message, error) bool) {
+		if err := options.Validate(); err != nil {
+			yield(sqwiggle.Message{}, err)
+			return
+		}
+		n := 0
+		for msg, err := range sqwiggle.Paginate(ctx, &o, list) {
+			if err != nil {
+				yield(msg, err)
+				return
+			}
+			if options.Past(msg) {
+				return
+			}
+			if !options.Match(msg) {
+				continue
+			}
+			if !yield(msg, nil) {
+				return
+			}
+			n++
+			if max > 0 && n >= max {
+				return
+			}
+		}
+	}
+}
+
+// ListStreamMessages is an implementation of the sqwiggle.API interface
+func (m *Mock) ListStreamMessages(streamID, page, limit int) ([]sqwiggle.Message, error) {
+	return m.ListStreamMessagesContext(context.Background(), streamID, page, limit)
+}
+
+// ListStreamMessagesContext is an implementation of the sqwiggle.API interface
+func (m *Mock) ListStreamMessagesContext(ctx context.Context, streamID, page, limit int) ([]sqwiggle.Message, error) {
+	m.record("ListStreamMessages", streamID, page, limit)
+	if m.ListStreamMessagesFunc == nil {
+		return nil, notImplemented("ListStreamMessages")
+	}
+	return m.ListStreamMessagesFunc(ctx, streamID, page, limit)
+}
+
+// GetMessage is an implementation of the sqwiggle.API interface
+func (m *Mock) GetMessage(id int) (sqwiggle.Message, error) {
+	return m.GetMessageContext(context.Background(), id)
+}
+
+// GetMessageContext is an implementation of the sqwiggle.API interface
+func (m *Mock) GetMessageContext(ctx context.Context, id int) (sqwiggle.Message, error) {
+	m.record("GetMessage", id)
+	if m.GetMessageFunc == nil {
+		return sqwiggle.Message{}, notImplemented("GetMessage")
+	}
+	return m.GetMessageFunc(ctx, id)
+}
+
+// PostMessage is an implementation of the sqwiggle.API interface
+func (m *Mock) PostMessage(streamID int, text string, options *sqwiggle.PostMessageOptions) (sqwiggle.Message, error) {
+	return m.PostMessageContext(context.Background(), streamID, text, options)
+}
+
+// PostMessageContext is an implementation of the sqwiggle.API interface
+func (m *Mock) PostMessageContext(ctx context.Context, streamID int, text string, options *sqwiggle.PostMessageOptions) (sqwiggle.Message, error) {
+	m.record("PostMessage", streamID, text, options)
+	if m.PostMessageFunc == nil {
+		return sqwiggle.Message{}, notImplemented("PostMessage")
+	}
+	return m.PostMessageFunc(ctx, streamID, text, options)
+}
+
+// UpdateMessage is an implementation of the sqwiggle.API interface
+func (m *Mock) UpdateMessage(id int, text string) (sqwiggle.Message, error) {
+	return m.UpdateMessageContext(context.Background(), id, text)
+}
+
+// UpdateMessageContext is an implementation of the sqwiggle.API interface
+func (m *Mock) UpdateMessageContext(ctx context.Context, id int, text string) (sqwiggle.Message, error) {
+	m.record("UpdateMessage", id, text)
+	if m.UpdateMessageFunc == nil {
+		return sqwiggle.Message{}, notImplemented("UpdateMessage")
+	}
+	return m.UpdateMessageFunc(ctx, id, text)
+}
+
+// DeleteMessage is an implementation of the sqwiggle.API interface
+func (m *Mock) DeleteMessage(id int) error {
+	return m.DeleteMessageContext(context.Background(), id)
+}
+
+// DeleteMessageContext is an implementation of the sqwiggle.API interface
+func (m *Mock) DeleteMessageContext(ctx context.Context, id int) error {
+	m.record("DeleteMessage", id)
+	if m.DeleteMessageFunc == nil {
+		return notImplemented("DeleteMessage")
+	}
+	return m.DeleteMessageFunc(ctx, id)
+}
+
+/*************************************************************************
+
+  Streams
+
+*************************************************************************/
+
+// ListStreams is an implementation of the sqwiggle.API interface
+func (m *Mock) ListStreams(page, limit int) ([]sqwiggle.Stream, error) {
+	return m.ListStreamsContext(context.Background(), page, limit)
+}
+
+// ListStreamsContext is an implementation of the sqwiggle.API interface
+func (m *Mock) ListStreamsContext(ctx context.Context, page, limit int) ([]sqwiggle.Stream, error) {
+	m.record("ListStreams", page, limit)
+	if m.ListStreamsFunc == nil {
+		return nil, notImplemented("ListStreams")
+	}
+	return m.ListStreamsFunc(ctx, page, limit)
+}
+
+// AllStreams is an implementation of the sqwiggle.API interface
+func (m *Mock) AllStreams(ctx context.Context, opts *sqwiggle.PageOptions) iter.Seq2[sqwiggle.Stream, error] {
+	return sqwiggle.Paginate(ctx, opts, m.ListStreamsContext)
+}
+
+// GetStream is an implementation of the sqwiggle.API interface
+func (m *Mock) GetStream(id int) (sqwiggle.Stream, error) {
+	return m.GetStreamContext(context.Background(), id)
+}
+
+// GetStreamContext is an implementation of the sqwiggle.API interface
+func (m *Mock) GetStreamContext(ctx context.Context, id int) (sqwiggle.Stream, error) {
+	m.record("GetStream", id)
+	if m.GetStreamFunc == nil {
+		return sqwiggle.Stream{}, notImplemented("GetStream")
+	}
+	return m.GetStreamFunc(ctx, id)
+}
+
+// PostStream is an implementation of the sqwiggle.API interface
+func (m *Mock) PostStream(name string) (sqwiggle.Stream, error) {
+	return m.PostStreamContext(context.Background(), name)
+}
+
+// PostStreamContext is an implementation of the sqwiggle.API interface
+func (m *Mock) PostStreamContext(ctx context.Context, name string) (sqwiggle.Stream, error) {
+	m.record("PostStream", name)
+	if m.PostStreamFunc == nil {
+		return sqwiggle.Stream{}, notImplemented("PostStream")
+	}
+	return m.PostStreamFunc(ctx, name)
+}
+
+// UpdateStream is an implementation of the sqwiggle.API interface
+func (m *Mock) UpdateStream(id int, name string) (sqwiggle.Stream, error) {
+	return m.UpdateStreamContext(context.Background(), id, name)
+}
+
+// UpdateStreamContext is an implementation of the sqwiggle.API interface
+func (m *Mock) UpdateStreamContext(ctx context.Context, id int, name string) (sqwiggle.Stream, error) {
+	m.record("UpdateStream", id, name)
+	if m.UpdateStreamFunc == nil {
+		return sqwiggle.Stream{}, notImplemented("UpdateStream")
+	}
+	return m.UpdateStreamFunc(ctx, id, name)
+}
+
+// DeleteStream is an implementation of the sqwiggle.API interface
+func (m *Mock) DeleteStream(id int) error {
+	return m.DeleteStreamContext(context.Background(), id)
+}
+
+// DeleteStreamContext is an implementation of the sqwiggle.API interface
+func (m *Mock) DeleteStreamContext(ctx context.Context, id int) error {
+	m.record("DeleteStream", id)
+	if m.DeleteStreamFunc == nil {
+		return notImplemented("DeleteStream")
+	}
+	return m.DeleteStreamFunc(ctx, id)
+}
+
+/*************************************************************************
+
+  Users
+
+*************************************************************************/
+
+// ListUsers is an implementation of the sqwiggle.API interface
+func (m *Mock) ListUsers(page, limit int) ([]sqwiggle.User, error) {
+	return m.ListUsersContext(context.Background(), page, limit)
+}
+
+// ListUsersContext is an implementation of the sqwiggle.API interface
+func (m *Mock) ListUsersContext(ctx context.Context, page, limit int) ([]sqwiggle.User, error) {
+	m.record("ListUsers", page, limit)
+	if m.ListUsersFunc == nil {
+		return nil, notImplemented("ListUsers")
+	}
+	return m.ListUsersFunc(ctx, page, limit)
+}
+
+// AllUsers is an implementation of the sqwiggle.API interface
+func (m *Mock) AllUsers(ctx context.Context, opts *sqwiggle.PageOptions) iter.Seq2[sqwiggle.User, error] {
+	return sqwiggle.Paginate(ctx, opts, m.ListUsersContext)
+}
+
+// GetUser is an implementation of the sqwiggle.API interface
+func (m *Mock) GetUser(id int) (sqwiggle.User, error) {
+	return m.GetUserContext(context.Background(), id)
+}
+
+// GetUserContext is an implementation of the sqwiggle.API interface
+func (m *Mock) GetUserContext(ctx context.Context, id int) (sqwiggle.User, error) {
+	m.record("GetUser", id)
+	if m.GetUserFunc == nil {
+		return sqwiggle.User{}, notImplemented("GetUser")
+	}
+	return m.GetUserFunc(ctx, id)
+}
+
+// UpdateUser is an implementation of the sqwiggle.API interface
+func (m *Mock) UpdateUser(id int, options *sqwiggle.UpdateUserOptions) (sqwiggle.User, error) {
+	return m.UpdateUserContext(context.Background(), id, options)
+}
+
+// UpdateUserContext is an implementation of the sqwiggle.API interface
+func (m *Mock) UpdateUserContext(ctx context.Context, id int, options *sqwiggle.UpdateUserOptions) (sqwiggle.User, error) {
+	m.record("UpdateUser", id, options)
+	if m.UpdateUserFunc == nil {
+		return sqwiggle.User{}, notImplemented("UpdateUser")
+	}
+	return m.UpdateUserFunc(ctx, id, options)
+}
+
+/*************************************************************************
+
+  Organizations
+
+*************************************************************************/
+
+// ListOrganizations is an implementation of the sqwiggle.API interface
+func (m *Mock) ListOrganizations(page, limit int) ([]sqwiggle.Organization, error) {
+	return m.ListOrganizationsContext(context.Background(), page, limit)
+}
+
+// ListOrganizationsContext is an implementation of the sqwiggle.API interface
+func (m *Mock) ListOrganizationsContext(ctx context.Context, page, limit int) ([]sqwiggle.Organization, error) {
+	m.record("ListOrganizations", page, limit)
+	if m.ListOrganizationsFunc == nil {
+		return nil, notImplemented("ListOrganizations")
+	}
+	return m.ListOrganizationsFunc(ctx, page, limit)
+}
+
+// AllOrganizations is an implementation of the sqwiggle.API interface
+func (m *Mock) AllOrganizations(ctx context.Context, opts *sqwiggle.PageOptions) iter.Seq2[sqwiggle.Organization, error] {
+	return sqwiggle.Paginate(ctx, opts, m.ListOrganizationsContext)
+}
+
+// GetOrganization is an implementation of the sqwiggle.API interface
+func (m *Mock) GetOrganization(id int) (sqwiggle.Organization, error) {
+	return m.GetOrganizationContext(context.Background(), id)
+}
+
+// GetOrganizationContext is an implementation of the sqwiggle.API interface
+func (m *Mock) GetOrganizationContext(ctx context.Context, id int) (sqwiggle.Organization, error) {
+	m.record("GetOrganization", id)
+	if m.GetOrganizationFunc == nil {
+		return sqwiggle.Organization{}, notImplemented("GetOrganization")
+	}
+	return m.GetOrganizationFunc(ctx, id)
+}
+
+// UpdateOrganization is an implementation of the sqwiggle.API interface
+func (m *Mock) UpdateOrganization(id int, options *sqwiggle.UpdateOrganizationOptions) (sqwiggle.Organization, error) {
+	return m.UpdateOrganizationContext(context.Background(), id, options)
+}
+
+// UpdateOrganizationContext is an implementation of the sqwiggle.API interface
+func (m *Mock) UpdateOrganizationContext(ctx context.Context, id int, options *sqwiggle.UpdateOrganizationOptions) (sqwiggle.Organization, error) {
+	m.record("UpdateOrganization", id, options)
+	if m.UpdateOrganizationFunc == nil {
+		return sqwiggle.Organization{}, notImplemented("UpdateOrganization")
+	}
+	return m.UpdateOrganizationFunc(ctx, id, options)
+}
+
+/*************************************************************************
+
+  Info
+
+*************************************************************************/
+
+// GetInfo is an implementation of the sqwiggle.API interface
+func (m *Mock) GetInfo() ([]byte, error) {
+	return m.GetInfoContext(context.Background())
+}
+
+// GetInfoContext is an implementation of the sqwiggle.API interface
+func (m *Mock) GetInfoContext(ctx context.Context) ([]byte, error) {
+	m.record("GetInfo")
+	if m.GetInfoFunc == nil {
+		return nil, notImplemented("GetInfo")
+	}
+	return m.GetInfoFunc(ctx)
+}
+
+// GetInfoTyped is an implementation of the sqwiggle.API interface
+func (m *Mock) GetInfoTyped() (sqwiggle.Info, error) {
+	return m.GetInfoTypedContext(context.Background())
+}
+
+// GetInfoTypedContext is an implementation of the sqwiggle.API interface
+func (m *Mock) GetInfoTypedContext(ctx context.Context) (sqwiggle.Info, error) {
+	m.record("GetInfoTyped")
+	if m.GetInfoTypedFunc == nil {
+		return sqwiggle.Info{}, notImplemented("GetInfoTyped")
+	}
+	return m.GetInfoTypedFunc(ctx)
+}
+
+/*************************************************************************
+
+  Conversations
+
+*************************************************************************/
+
+// ListConversations is an implementation of the sqwiggle.API interface
+func (m *Mock) ListConversations(page, limit int) ([]sqwiggle.Conversation, error) {
+	return m.ListConversationsContext(context.Background(), page, limit)
+}
+
+// ListConversationsContext is an implementation of the sqwiggle.API interface
+func (m *Mock) ListConversationsContext(ctx context.Context, page, limit int) ([]sqwiggle.Conversation, error) {
+	m.record("ListConversations", page, limit)
+	if m.ListConversationsFunc == nil {
+		return nil, notImplemented("ListConversations")
+	}
+	return m.ListConversationsFunc(ctx, page, limit)
+}
+
+// AllConversations is an implementation of the sqwiggle.API interface
+func (m *Mock) AllConversations(ctx context.Context, opts *sqwiggle.PageOptions) iter.Seq2[sqwiggle.Conversation, error] {
+	return sqwiggle.Paginate(ctx, opts, m.ListConversationsContext)
+}
+
+// GetConversation is an implementation of the sqwiggle.API interface
+func (m *Mock) GetConversation(id int) (sqwiggle.Conversation, error) {
+	return m.GetConversationContext(context.Background(), id)
+}
+
+// GetConversationContext is an implementation of the sqwiggle.API interface
+func (m *Mock) GetConversationContext(ctx context.Context, id int) (sqwiggle.Conversation, error) {
+	m.record("GetConversation", id)
+	if m.GetConversationFunc == nil {
+		return sqwiggle.Conversation{}, notImplemented("GetConversation")
+	}
+	return m.GetConversationFunc(ctx, id)
+}
+
+/*************************************************************************
+
+  Invites
+
+*************************************************************************/
+
+// ListInvites is an implementation of the sqwiggle.API interface
+func (m *Mock) ListInvites(page, limit int) ([]sqwiggle.Invite, error) {
+	return m.ListInvitesContext(context.Background(), page, limit)
+}
+
+// ListInvitesContext is an implementation of the sqwiggle.API interface
+func (m *Mock) ListInvitesContext(ctx context.Context, page, limit int) ([]sqwiggle.Invite, error) {
+	m.record("ListInvites", page, limit)
+	if m.ListInvitesFunc == nil {
+		return nil, notImplemented("ListInvites")
+	}
+	return m.ListInvitesFunc(ctx, page, limit)
+}
+
+// AllInvites is an implementation of the sqwiggle.API interface
+func (m *Mock) AllInvites(ctx context.Context, opts *sqwiggle.PageOptions) iter.Seq2[sqwiggle.Invite, error] {
+	return sqwiggle.Paginate(ctx, opts, m.ListInvitesContext)
+}
+
+// GetInvite is an implementation of the sqwiggle.API interface
+func (m *Mock) GetInvite(id int) (sqwiggle.Invite, error) {
+	return m.GetInviteContext(context.Background(), id)
+}
+
+// GetInviteContext is an implementation of the sqwiggle.API interface
+func (m *Mock) GetInviteContext(ctx context.Context, id int) (sqwiggle.Invite, error) {
+	m.record("GetInvite", id)
+	if m.GetInviteFunc == nil {
+		return sqwiggle.Invite{}, notImplemented("GetInvite")
+	}
+	return m.GetInviteFunc(ctx, id)
+}
+
+// PostInvite is an implementation of the sqwiggle.API interface
+func (m *Mock) PostInvite(email string) (sqwiggle.Invite, error) {
+	return m.PostInviteContext(context.Background(), email)
+}
+
+// PostInviteContext is an implementation of the sqwiggle.API interface
+func (m *Mock) PostInviteContext(ctx context.Context, email string) (sqwiggle.Invite, error) {
+	m.record("PostInvite", email)
+	if m.PostInviteFunc == nil {
+		return sqwiggle.Invite{}, notImplemented("PostInvite")
+	}
+	return m.PostInviteFunc(ctx, email)
+}
+
+// DeleteInvite is an implementation of the sqwiggle.API interface
+func (m *Mock) DeleteInvite(id int) error {
+	return m.DeleteInviteContext(context.Background(), id)
+}
+
+// DeleteInviteContext is an implementation of the sqwiggle.API interface
+func (m *Mock) DeleteInviteContext(ctx context.Context, id int) error {
+	m.record("DeleteInvite", id)
+	if m.DeleteInviteFunc == nil {
+		return notImplemented("DeleteInvite")
+	}
+	return m.DeleteInviteFunc(ctx, id)
+}
+
+/*************************************************************************
+
+  Attachments
+
+*************************************************************************/
+
+// ListAttachments is an implementation of the sqwiggle.API interface
+func (m *Mock) ListAttachments(page, limit int) ([]sqwiggle.Attachment, error) {
+	return m.ListAttachmentsContext(context.Background(), page, limit)
+}
+
+// ListAttachmentsContext is an implementation of the sqwiggle.API interface
+func (m *Mock) ListAttachmentsContext(ctx context.Context, page, limit int) ([]sqwiggle.Attachment, error) {
+	m.record("ListAttachments", page, limit)
+	if m.ListAttachmentsFunc == nil {
+		return nil, notImplemented("ListAttachments")
+	}
+	return m.ListAttachmentsFunc(ctx, page, limit)
+}
+
+// AllAttachments is an implementation of the sqwiggle.API interface
+func (m *Mock) AllAttachments(ctx context.Context, opts *sqwiggle.PageOptions) iter.Seq2[sqwiggle.Attachment, error] {
+	return sqwiggle.Paginate(ctx, opts, m.ListAttachmentsContext)
+}
+
+// GetAttachment is an implementation of the sqwiggle.API interface
+func (m *Mock) GetAttachment(id int) (sqwiggle.Attachment, error) {
+	return m.GetAttachmentContext(context.Background(), id)
+}
+
+// GetAttachmentContext is an implementation of the sqwiggle.API interface
+func (m *Mock) GetAttachmentContext(ctx context.Context, id int) (sqwiggle.Attachment, error) {
+	m.record("GetAttachment", id)
+	if m.GetAttachmentFunc == nil {
+		return sqwiggle.Attachment{}, notImplemented("GetAttachment")
+	}
+	return m.GetAttachmentFunc(ctx, id)
+}
+
+// PostAttachment is an implementation of the sqwiggle.API interface
+func (m *Mock) PostAttachment(name string) (sqwiggle.Attachment, error) {
+	return m.PostAttachmentContext(context.Background(), name)
+}
+
+// PostAttachmentContext is an implementation of the sqwiggle.API interface
+func (m *Mock) PostAttachmentContext(ctx context.Context, name string) (sqwiggle.Attachment, error) {
+	m.record("PostAttachment", name)
+	if m.PostAttachmentFunc == nil {
+		return sqwiggle.Attachment{}, notImplemented("PostAttachment")
+	}
+	return m.PostAttachmentFunc(ctx, name)
+}
+
+// UpdateAttachment is an implementation of the sqwiggle.API interface
+func (m *Mock) UpdateAttachment(id int, options *sqwiggle.UpdateAttachmentOptions) (sqwiggle.Attachment, error) {
+	return m.UpdateAttachmentContext(context.Background(), id, options)
+}
+
+// UpdateAttachmentContext is an implementation of the sqwiggle.API interface
+func (m *Mock) UpdateAttachmentContext(ctx context.Context, id int, options *sqwiggle.UpdateAttachmentOptions) (sqwiggle.Attachment, error) {
+	m.record("UpdateAttachment", id, options)
+	if m.UpdateAttachmentFunc == nil {
+		return sqwiggle.Attachment{}, notImplemented("UpdateAttachment")
+	}
+	return m.UpdateAttachmentFunc(ctx, id, options)
+}
+
+// DeleteAttachment is an implementation of the sqwiggle.API interface
+func (m *Mock) DeleteAttachment(id int) error {
+	return m.DeleteAttachmentContext(context.Background(), id)
+}
+
+// DeleteAttachmentContext is an implementation of the sqwiggle.API interface
+func (m *Mock) DeleteAttachmentContext(ctx context.Context, id int) error {
+	m.record("DeleteAttachment", id)
+	if m.DeleteAttachmentFunc == nil {
+		return notImplemented("DeleteAttachment")
+	}
+	return m.DeleteAttachmentFunc(ctx, id)
+}
@@ -0,0 +1,107 @@
+package sqwiggletest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hermanschaaf/sqwiggle"
+)
+
+// announce is an example of code under test that depends on the API.
+func announce(api sqwiggle.MessagesAPI, streamID int, text string) (int, error) {
+	m, err := api.PostMessage(streamID, text, nil)
+	return m.ID, err
+}
+
+func TestMock(t *testing.T) {
+	mock := &Mock{
+		PostMessageFunc: func(ctx context.Context, streamID int, text string, options *sqwiggle.PostMessageOptions) (sqwiggle.Message, error) {
+			return sqwiggle.Message{ID: 42, StreamID: streamID, Text: text}, nil
+		},
+	}
+
+	id, err := announce(mock, 7, "Deploying!")
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if id != 42 {
+		t.Errorf("id = %d, want %d", id, 42)
+	}
+
+	want := []Call{{Method: "PostMessage", Args: []interface{}{7, "Deploying!", (*sqwiggle.PostMessageOptions)(nil)}}}
+	if got := mock.Calls("PostMessage"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls = %#v, want %#v", got, want)
+	}
+
+	// operations without a function fail
+	if err := mock.DeleteMessage(42); err == nil {
+		t.Error("DeleteMessage without DeleteMessageFunc succeeded, want error")
+	}
+	if n := len(mock.Calls("")); n != 2 {
+		t.Errorf("len(Calls) = %d, want %d", n, 2)
+	}
+}
+
+func TestMock_All(t *testing.T) {
+	mock := &Mock{
+		ListUsersFunc: func(ctx context.Context, page, limit int) ([]sqwiggle.User, error) {
+			if page > 2 {
+				return nil, errors.New("too far")
+			}
+			users := make([]sqwiggle.User, limit)
+			for i := range users {
+				users[i].ID = (page-1)*limit + i + 1
+			}
+			return users, nil
+		},
+	}
+
+	n := 0
+	for u, err := range mock.AllUsers(context.Background(), &sqwiggle.PageOptions{PageSize: 2, MaxItems: 3}) {
+		if err != nil {
+			t.Fatal("got error:", err)
+		}
+		n++
+		if u.ID != n {
+			t.Errorf("user #%d has ID %d", n, u.ID)
+		}
+	}
+	if n != 3 {
+		t.Errorf("got %d users, want %d", n, 3)
+	}
+	if calls := mock.Calls("ListUsers"); len(calls) != 2 {
+		t.Errorf("ListUsers called %d times, want %d", len(calls), 2)
+	}
+}
+
+func TestMock_AllMessagesWithOptions(t *testing.T) {
+	// messages 10 down to 1, alternating between authors 1 and 2
+	mock := &Mock{
+		ListStreamMessagesFunc: func(ctx context.Context, streamID, page, limit int) ([]sqwiggle.Message, error) {
+			msgs := []sqwiggle.Message{}
+			for i := (page-1)*limit + 1; i <= page*limit && i <= 10; i++ {
+				id := 11 - i
+				msgs = append(msgs, sqwiggle.Message{ID: id, StreamID: streamID, Author: sqwiggle.User{ID: 1 + id%2}})
+			}
+			return msgs, nil
+		},
+	}
+
+	// filtered pages are short, but do not end the iteration
+	var got []int
+	options := &sqwiggle.ListMessagesOptions{StreamID: 1, AuthorID: 1, AfterID: 3}
+	for msg, err := range mock.AllMessagesWithOptions(context.Background(), &sqwiggle.PageOptions{PageSize: 3}, options) {
+		if err != nil {
+			t.Fatal("got error:", err)
+		}
+		got = append(got, msg.ID)
+	}
+	if want := []int{10, 8, 6, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got messages %v, want %v", got, want)
+	}
+	if calls := mock.Calls("ListStreamMessages"); len(calls) != 3 {
+		t.Errorf("ListStreamMessages called %d times, want %d", len(calls), 3)
+	}
+}
@@ -0,0 +1,422 @@
+// Package sqwiggletest provides a fake, in-memory implementation of the
+// Sqwiggle API for use in tests.
+//
+// A Server keeps track of messages, streams, users, invites, attachments,
+// conversations and organizations, so that changes made through the API
+// are visible in later requests, just like with the real API:
+//
+//	server := sqwiggletest.NewServer("test-key")
+//	defer server.Close()
+//
+//	client := server.Client()
+//	stream, _ := client.PostStream("General")
+//	client.PostMessage(stream.ID, "Hello!", nil)
+//
+// Requests must authenticate with the server's API key using HTTP Basic
+// auth, as the Client does; other requests fail with an authentication
+// error.
+package sqwiggletest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hermanschaaf/sqwiggle"
+)
+
+// defaultLimit is the page size used when a request does not specify one.
+const defaultLimit = 50
+
+// Server is a fake Sqwiggle API server, backed by memory. The zero value
+// is not usable; create Servers with NewServer.
+type Server struct {
+	*httptest.Server
+
+	// APIKey is the key requests must authenticate with.
+	APIKey string
+
+	// Now returns the current time, and is used to set timestamps on
+	// created and updated resources. It defaults to time.Now.
+	Now func() time.Time
+
+	mu            sync.Mutex
+	nextID        int
+	self          int // ID of the user the API key belongs to
+	info          json.RawMessage
+	messages      map[int]*sqwiggle.Message
+	streams       map[int]*sqwiggle.Stream
+	users         map[int]*sqwiggle.User
+	invites       map[int]*sqwiggle.Invite
+	attachments   map[int]*sqwiggle.Attachment
+	conversations map[int]*sqwiggle.Conversation
+	organizations map[int]*sqwiggle.Organization
+}
+
+// NewServer starts and returns a new Server, which accepts requests
+// authenticated with apiKey. The server starts out with a single
+// organization and a single user, the owner of the API key, who is the
+// author of all messages posted through the API. The caller should call
+// Close when finished, to shut it down.
+func NewServer(apiKey string) *Server {
+	s := &Server{
+		APIKey:        apiKey,
+		Now:           time.Now,
+		info:          json.RawMessage(defaultInfo),
+		messages:      make(map[int]*sqwiggle.Message),
+		streams:       make(map[int]*sqwiggle.Stream),
+		users:         make(map[int]*sqwiggle.User),
+		invites:       make(map[int]*sqwiggle.Invite),
+		attachments:   make(map[int]*sqwiggle.Attachment),
+		conversations: make(map[int]*sqwiggle.Conversation),
+		organizations: make(map[int]*sqwiggle.Organization),
+	}
+	s.AddOrganization(sqwiggle.Organization{Name: "Test Organization", Path: "test-organization"})
+	s.self = s.AddUser(sqwiggle.User{
+		Name:      "Test User",
+		Email:     "test@example.com",
+		Role:      sqwiggle.RoleOwner,
+		Status:    sqwiggle.StatusAvailable,
+		Type:      sqwiggle.TypeUser,
+		Confirmed: true,
+	}).ID
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a Client configured to talk to this server.
+func (s *Server) Client() *sqwiggle.Client {
+	c := sqwiggle.NewClient(s.APIKey)
+	c.RootURL = s.URL
+	c.HTTPClient = s.Server.Client()
+	return c
+}
+
+// Self returns the user that the server's API key belongs to.
+func (s *Server) Self() sqwiggle.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.users[s.self]
+}
+
+// SetInfo replaces the response to GET /info.
+func (s *Server) SetInfo(info json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info = info
+}
+
+// id returns a new unique ID. s.mu must be held.
+func (s *Server) id() int {
+	s.nextID++
+	return s.nextID
+}
+
+// now returns the current time, rounded to the precision of the API.
+func (s *Server) now() time.Time {
+	return s.Now().UTC().Truncate(time.Millisecond)
+}
+
+// AddMessage stores m, assigning it an ID and creation time if it does
+// not have them yet, and returns the stored message.
+func (s *Server) AddMessage(m sqwiggle.Message) sqwiggle.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m.ID == 0 {
+		m.ID = s.id()
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = s.now()
+	}
+	if m.UpdatedAt.IsZero() {
+		m.UpdatedAt = m.CreatedAt
+	}
+	if m.Attachments == nil {
+		m.Attachments = []sqwiggle.Attachment{}
+	}
+	if m.Mentions == nil {
+		m.Mentions = []sqwiggle.Mention{}
+	}
+	s.messages[m.ID] = &m
+	return m
+}
+
+// AddStream stores st, assigning it an ID and creation time if it does
+// not have them yet, and returns the stored stream.
+func (s *Server) AddStream(st sqwiggle.Stream) sqwiggle.Stream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st.ID == 0 {
+		st.ID = s.id()
+	}
+	if st.CreatedAt.IsZero() {
+		st.CreatedAt = s.now()
+	}
+	if st.Path == "" {
+		st.Path = pathFor(st.Name)
+	}
+	s.streams[st.ID] = &st
+	return st
+}
+
+// AddUser stores u, assigning it an ID and creation time if it does not
+// have them yet, and returns the stored user.
+func (s *Server) AddUser(u sqwiggle.User) sqwiggle.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u.ID == 0 {
+		u.ID = s.id()
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = s.now()
+	}
+	s.users[u.ID] = &u
+	return u
+}
+
+// AddInvite stores i, assigning it an ID and creation time if it does not
+// have them yet, and returns the stored invite.
+func (s *Server) AddInvite(i sqwiggle.Invite) sqwiggle.Invite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i.ID == 0 {
+		i.ID = s.id()
+	}
+	if i.CreatedAt.IsZero() {
+		i.CreatedAt = s.now()
+	}
+	s.invites[i.ID] = &i
+	return i
+}
+
+// AddAttachment stores a, assigning it an ID and creation time if it does
+// not have them yet, and returns the stored attachment. If messageID is
+// not zero, the attachment is also added to that message.
+func (s *Server) AddAttachment(messageID int, a sqwiggle.Attachment) sqwiggle.Attachment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a.ID == 0 {
+		a.ID = s.id()
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = s.now()
+	}
+	if a.UpdatedAt.IsZero() {
+		a.UpdatedAt = a.CreatedAt
+	}
+	s.attachments[a.ID] = &a
+	if m, ok := s.messages[messageID]; ok {
+		m.Attachments = append(m.Attachments, a)
+	}
+	return a
+}
+
+// AddConversation stores c, assigning it an ID and creation time if it
+// does not have them yet, and returns the stored conversation.
+func (s *Server) AddConversation(c sqwiggle.Conversation) sqwiggle.Conversation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.ID == 0 {
+		c.ID = s.id()
+	}
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = s.now()
+	}
+	if c.Status == "" {
+		c.Status = sqwiggle.ConversationOpen
+	}
+	s.conversations[c.ID] = &c
+	return c
+}
+
+// AddOrganization stores o, assigning it an ID and creation time if it
+// does not have them yet, and returns the stored organization.
+func (s *Server) AddOrganization(o sqwiggle.Organization) sqwiggle.Organization {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o.ID == 0 {
+		o.ID = s.id()
+	}
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = s.now()
+	}
+	if o.Path == "" {
+		o.Path = pathFor(o.Name)
+	}
+	s.organizations[o.ID] = &o
+	return o
+}
+
+// pathFor turns a name into a URL path, the way Sqwiggle does for streams
+// and organizations.
+func pathFor(name string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, name), "-")
+}
+
+// serveHTTP authenticates requests and routes them to the handler for
+// the requested resource.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, _, ok := r.BasicAuth(); !ok || user != s.APIKey {
+		writeError(w, http.StatusUnauthorized, sqwiggle.Error{
+			Type:    sqwiggle.ErrAuthentication,
+			Message: "Sorry, your account could not be authenticated",
+			Details: "Did you provide an auth_token?",
+		})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, sqwiggle.Error{
+			Type:    sqwiggle.ErrInvalidParam,
+			Message: "The request body could not be parsed",
+		})
+		return
+	}
+
+	// paths look like /:resource, /:resource/:id or, for the messages of
+	// a stream, /streams/:id/messages
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	id := 0
+	if len(parts) > 1 {
+		var err error
+		nested := len(parts) == 3 && parts[0] == "streams" && parts[2] == "messages"
+		if id, err = strconv.Atoi(parts[1]); err != nil || (len(parts) > 2 && !nested) {
+			notFound(w)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(parts) == 3 {
+		s.serveStreamMessages(w, r, id)
+		return
+	}
+	switch parts[0] {
+	case "messages":
+		s.serveMessages(w, r, id)
+	case "streams":
+		s.serveStreams(w, r, id)
+	case "users":
+		s.serveUsers(w, r, id)
+	case "invites":
+		s.serveInvites(w, r, id)
+	case "attachments":
+		s.serveAttachments(w, r, id)
+	case "conversations":
+		s.serveConversations(w, r, id)
+	case "organizations":
+		s.serveOrganizations(w, r, id)
+	case "info":
+		if r.Method != "GET" || id != 0 {
+			methodNotAllowed(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.info)
+	default:
+		notFound(w)
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an API error response.
+func writeError(w http.ResponseWriter, code int, err sqwiggle.Error) {
+	writeJSON(w, code, err)
+}
+
+func notFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, sqwiggle.Error{
+		Type:    sqwiggle.ErrUnknown,
+		Message: "The requested resource could not be found",
+	})
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, sqwiggle.Error{
+		Type:    sqwiggle.ErrUnknown,
+		Message: "The requested method is not supported for this resource",
+	})
+}
+
+func invalidParam(w http.ResponseWriter, param, message string) {
+	writeError(w, http.StatusBadRequest, sqwiggle.Error{
+		Type:    sqwiggle.ErrInvalidParam,
+		Message: message,
+		Param:   param,
+	})
+}
+
+// checkParams fails the request with an ErrUnknownParam error and returns
+// false if the form holds parameters other than the allowed ones.
+func checkParams(w http.ResponseWriter, r *http.Request, allowed ...string) bool {
+	for k := range r.PostForm {
+		known := false
+		for _, a := range allowed {
+			known = known || k == a
+		}
+		if !known {
+			writeError(w, http.StatusBadRequest, sqwiggle.Error{
+				Type:    sqwiggle.ErrUnknownParam,
+				Message: "Unknown parameter " + k,
+				Param:   k,
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// paginate returns the page of items requested by r.
+func paginate[T any](r *http.Request, items []T) []T {
+	page, _ := strconv.Atoi(r.Form.Get("page"))
+	limit, _ := strconv.Atoi(r.Form.Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultLimit
+	}
+	start := (page - 1) * limit
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// values returns the values of m, sorted with less.
+func values[T any](m map[int]*T, less func(a, b *T) bool) []T {
+	ptrs := make([]*T, 0, len(m))
+	for _, v := range m {
+		ptrs = append(ptrs, v)
+	}
+	sort.Slice(ptrs, func(i, j int) bool { return less(ptrs[i], ptrs[j]) })
+	items := make([]T, len(ptrs))
+	for i, p := range ptrs {
+		items[i] = *p
+	}
+	return items
+}
@@ -0,0 +1,296 @@
+package sqwiggletest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hermanschaaf/sqwiggle"
+)
+
+func TestServer_Messages(t *testing.T) {
+	server := NewServer("test")
+	defer server.Close()
+	client := server.Client()
+
+	stream, err := client.PostStream("General Chat")
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if stream.Path != "general-chat" {
+		t.Errorf("Path = %q, want %q", stream.Path, "general-chat")
+	}
+
+	self := server.Self()
+	first, err := client.PostMessage(stream.ID, "Hello!", nil)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if first.Author.ID != self.ID || first.StreamID != stream.ID {
+		t.Errorf("got message %+v, want author %d in stream %d", first, self.ID, stream.ID)
+	}
+	second, err := client.PostMessage(stream.ID, fmt.Sprintf("Hi @(Test User)[user:%d]!", self.ID), nil)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if second.Text != "Hi Test User!" {
+		t.Errorf("Text = %q, want %q", second.Text, "Hi Test User!")
+	}
+	if len(second.Mentions) != 1 || second.Mentions[0].SubjectID != self.ID ||
+		second.Mentions[0].Indices[0] != 3 || second.Mentions[0].Indices[1] != 12 {
+		t.Errorf("Mentions = %+v, want a mention of user %d at [3 12]", second.Mentions, self.ID)
+	}
+
+	// messages are listed newest first
+	msgs, err := client.ListMessages(0, 0)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if len(msgs) != 2 || msgs[0].ID != second.ID || msgs[1].ID != first.ID {
+		t.Errorf("ListMessages returned %+v, want messages %d and %d", msgs, second.ID, first.ID)
+	}
+
+	updated, err := client.UpdateMessage(first.ID, "Hello again!")
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if updated.Text != "Hello again!" {
+		t.Errorf("Text = %q, want %q", updated.Text, "Hello again!")
+	}
+
+	if err := client.DeleteMessage(first.ID); err != nil {
+		t.Fatal("got error:", err)
+	}
+	removed, err := client.GetMessage(first.ID)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if removed.Text != "This message has been removed" {
+		t.Errorf("Text = %q, want the removal note", removed.Text)
+	}
+
+	// posting to a stream that does not exist fails
+	_, err = client.PostMessage(12345, "Hello?", nil)
+	var apiErr *sqwiggle.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 400 || apiErr.Err.Param != "stream_id" {
+		t.Errorf("err = %v, want invalid stream_id", err)
+	}
+}
+
+func TestServer_Streams(t *testing.T) {
+	server := NewServer("test")
+	defer server.Close()
+	client := server.Client()
+
+	for _, name := range []string{"Zebras", "Aardvarks", "Moose"} {
+		if _, err := client.PostStream(name); err != nil {
+			t.Fatal("got error:", err)
+		}
+	}
+
+	// streams are listed in alphabetical order, and paginated
+	streams, err := client.ListStreams(1, 2)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if len(streams) != 2 || streams[0].Name != "Aardvarks" || streams[1].Name != "Moose" {
+		t.Errorf("ListStreams(1, 2) = %+v, want Aardvarks and Moose", streams)
+	}
+
+	s, err := client.UpdateStream(streams[0].ID, "Anteaters")
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if s.Name != "Anteaters" || s.Path != "anteaters" {
+		t.Errorf("got stream %+v, want it renamed to Anteaters", s)
+	}
+
+	if err := client.DeleteStream(s.ID); err != nil {
+		t.Fatal("got error:", err)
+	}
+	_, err = client.GetStream(s.ID)
+	var apiErr *sqwiggle.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 404 {
+		t.Errorf("err = %v, want 404 error", err)
+	}
+}
+
+func TestServer_Users(t *testing.T) {
+	server := NewServer("test")
+	defer server.Close()
+	client := server.Client()
+
+	other := server.AddUser(sqwiggle.User{Name: "Other", Status: sqwiggle.StatusOffline})
+
+	users, err := client.ListUsers(0, 0)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("len(users) = %d, want %d", len(users), 2)
+	}
+
+	u, err := client.UpdateUser(other.ID, &sqwiggle.UpdateUserOptions{
+		Status:  sqwiggle.Status(sqwiggle.StatusBusy),
+		Message: sqwiggle.String("Lunch"),
+	})
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if u.Status != sqwiggle.StatusBusy || u.Message != "Lunch" {
+		t.Errorf("got user %+v, want busy with message Lunch", u)
+	}
+
+	// the client validates its options, so check the validation of the
+	// server with raw requests
+	tests := []struct {
+		values url.Values
+		want   sqwiggle.ErrorType
+	}{
+		{url.Values{"status": {"asleep"}}, sqwiggle.ErrInvalidParam},
+		{url.Values{"snapshot_interval": {"30"}}, sqwiggle.ErrInvalidParam},
+		{url.Values{"shoe_size": {"12"}}, sqwiggle.ErrUnknownParam},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("PUT", fmt.Sprintf("%s/users/%d", server.URL, other.ID), strings.NewReader(tt.values.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(server.APIKey, "X")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("got error:", err)
+		}
+		var e sqwiggle.Error
+		err = json.NewDecoder(resp.Body).Decode(&e)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusBadRequest || e.Type != tt.want {
+			t.Errorf("PUT /users/%d %v: got %d %+v, want %q", other.ID, tt.values, resp.StatusCode, e, tt.want)
+		}
+	}
+}
+
+func TestServer_StreamMessages(t *testing.T) {
+	server := NewServer("test")
+	defer server.Close()
+	client := server.Client()
+
+	general := server.AddStream(sqwiggle.Stream{Name: "General"})
+	random := server.AddStream(sqwiggle.Stream{Name: "Random"})
+	first := server.AddMessage(sqwiggle.Message{StreamID: general.ID, Text: "one"})
+	server.AddMessage(sqwiggle.Message{StreamID: random.ID, Text: "two"})
+	third := server.AddMessage(sqwiggle.Message{StreamID: general.ID, Text: "three"})
+
+	msgs, err := client.ListStreamMessages(general.ID, 0, 0)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if len(msgs) != 2 || msgs[0].ID != third.ID || msgs[1].ID != first.ID {
+		t.Errorf("ListStreamMessages returned %+v, want messages %d and %d", msgs, third.ID, first.ID)
+	}
+
+	// a stream that does not exist is an error
+	msgs, err = client.ListStreamMessages(12345, 0, 0)
+	var apiErr *sqwiggle.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("ListStreamMessages of a missing stream = %+v, %v, want a 404 error", msgs, err)
+	}
+}
+
+func TestServer_InvitesAndAttachments(t *testing.T) {
+	server := NewServer("test")
+	defer server.Close()
+	client := server.Client()
+
+	invite, err := client.PostInvite("new@example.com")
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if err := client.DeleteInvite(invite.ID); err != nil {
+		t.Fatal("got error:", err)
+	}
+	if invites, _ := client.ListInvites(0, 0); len(invites) != 0 {
+		t.Errorf("ListInvites returned %+v after deleting the only invite", invites)
+	}
+
+	// deleting the only attachment of a message removes the message too
+	stream := server.AddStream(sqwiggle.Stream{Name: "Files"})
+	m := server.AddMessage(sqwiggle.Message{StreamID: stream.ID, Text: "see attached"})
+	a := server.AddAttachment(m.ID, sqwiggle.Attachment{Type: sqwiggle.TypeFile, Title: "report.pdf", Status: "pending"})
+
+	a, err = client.UpdateAttachment(a.ID, &sqwiggle.UpdateAttachmentOptions{Status: sqwiggle.String(sqwiggle.AttachmentUploaded)})
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	got, _ := client.GetMessage(m.ID)
+	if len(got.Attachments) != 1 || got.Attachments[0].Status != "uploaded" {
+		t.Errorf("message attachments = %+v, want the updated attachment", got.Attachments)
+	}
+
+	if err := client.DeleteAttachment(a.ID); err != nil {
+		t.Fatal("got error:", err)
+	}
+	got, _ = client.GetMessage(m.ID)
+	if got.Text != "This message has been removed" {
+		t.Errorf("Text = %q, want the removal note", got.Text)
+	}
+}
+
+func TestServer_OrganizationsConversationsInfo(t *testing.T) {
+	server := NewServer("test")
+	defer server.Close()
+	client := server.Client()
+
+	orgs, err := client.ListOrganizations(0, 0)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if len(orgs) != 1 {
+		t.Fatalf("len(orgs) = %d, want %d", len(orgs), 1)
+	}
+	o, err := client.UpdateOrganization(orgs[0].ID, &sqwiggle.UpdateOrganizationOptions{Name: sqwiggle.String("Acme")})
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if o.Name != "Acme" {
+		t.Errorf("Name = %q, want %q", o.Name, "Acme")
+	}
+
+	c := server.AddConversation(sqwiggle.Conversation{Participating: []sqwiggle.User{server.Self()}})
+	got, err := client.GetConversation(c.ID)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if got.Status != sqwiggle.ConversationOpen || len(got.Participating) != 1 {
+		t.Errorf("got conversation %+v, want open conversation with one participant", got)
+	}
+
+	if _, err := client.GetInfo(); err != nil {
+		t.Fatal("got error:", err)
+	}
+	info, err := client.GetInfoTyped()
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	if info.Configuration.MaxFreeStreams != 5 {
+		t.Errorf("MaxFreeStreams = %d, want %d", info.Configuration.MaxFreeStreams, 5)
+	}
+}
+
+func TestServer_Authentication(t *testing.T) {
+	server := NewServer("test")
+	defer server.Close()
+
+	client := server.Client()
+	client.APIKey = "wrong"
+
+	_, err := client.ListStreams(0, 0)
+	if !errors.Is(err, sqwiggle.ErrAuthentication) {
+		t.Errorf("err = %v, want %q", err, sqwiggle.ErrAuthentication)
+	}
+}
@@ -19,7 +19,8 @@ type attr struct {
 var errNilInterface = fmt.Errorf("One of the interfaces is nil")
 
 // compare compares two structs and returns a map containing the differences between them.
-// This is not optimized for efficiency, and is only meant to be used in testing. It
+// This is not optimized for efficiency, and is used in testing and to build update
+// options from two versions of a resource (see DiffAttachment). It
 // is not a recursive function, and structs within the given struct will be directly
 // compared using reflect.DeepEqual.
 func compare(a interface{}, b interface{}) (diff difference, err error) {
@@ -0,0 +1,217 @@
+package sqwiggle
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracer starts spans that trace the work done by a Client. It is a
+// minimal interface, so that it can be implemented on top of any
+// tracing library, such as OpenTelemetry.
+//
+// The Client starts one span per API operation, named after the
+// operation (e.g. "GetMessage"), and a child span named after the HTTP
+// method (e.g. "HTTP GET") for every attempt made to perform it.
+type Tracer interface {
+	// Start starts a span called name as a child of the span in ctx,
+	// if any, and returns a context holding the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced unit of work, started by a Tracer.
+type Span interface {
+	SetAttribute(key string, value interface{}) // Annotates the span
+	RecordError(err error)                      // Marks the span as failed
+	End()                                       // Ends the span
+	SpanContext() SpanContext                   // Identifies the span, for propagation
+}
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// Traceparent returns the W3C traceparent header value for the span.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// These constants are the keys of the attributes the Client sets on spans.
+const (
+	AttrOperation  = "sqwiggle.operation"   // Name of the API operation
+	AttrResource   = "sqwiggle.resource"    // Kind of resource, such as "messages"
+	AttrResourceID = "sqwiggle.resource_id" // ID of the resource, if any
+	AttrErrorType  = "sqwiggle.error_type"  // Type of the API error, if any
+	AttrAttempt    = "sqwiggle.attempt"     // Number of the attempt, starting at 1
+	AttrMethod     = "http.method"          // HTTP method of the request
+	AttrPath       = "http.path"            // URL path of the request
+	AttrStatusCode = "http.status_code"     // HTTP status code of the response
+)
+
+// operationKey is the context key of the operation started by
+// Client.startOperation.
+type operationKey struct{}
+
+// operation is an API operation that makes several requests, traced as a
+// single span with the attempts of all requests as its children.
+type operation struct {
+	span Span
+	req  *http.Request // Last request made for the operation
+	resp *response     // Response to the last request
+}
+
+// startOperation starts the span of the API operation op, for methods that
+// make several requests, such as PostStream when it checks the stream
+// limit first. The requests made with the returned context do not start
+// spans of their own. The returned function ends the span with the
+// error of the method. It does nothing if the client has no Tracer, or
+// if ctx already belongs to an operation.
+func (c *Client) startOperation(ctx context.Context, op string) (context.Context, func(error)) {
+	if c.Tracer == nil || ctx.Value(operationKey{}) != nil {
+		return ctx, func(error) {}
+	}
+	o := &operation{}
+	ctx, o.span = c.Tracer.Start(ctx, op)
+	ctx = context.WithValue(ctx, operationKey{}, o)
+	return ctx, func(err error) {
+		endOperationSpan(o.span, op, o.req, o.resp, err)
+	}
+}
+
+// endOperationSpan annotates and ends the span of an API operation.
+func endOperationSpan(span Span, op string, req *http.Request, resp *response, err error) {
+	span.SetAttribute(AttrOperation, op)
+	if req != nil {
+		span.SetAttribute(AttrMethod, req.Method)
+		span.SetAttribute(AttrPath, req.URL.Path)
+
+		// paths look like /:resource or /:resource/:id
+		parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+		span.SetAttribute(AttrResource, parts[0])
+		if len(parts) > 1 {
+			if id, err := strconv.Atoi(parts[1]); err == nil {
+				span.SetAttribute(AttrResourceID, id)
+			}
+		}
+	}
+	endSpan(span, resp, err)
+}
+
+// endAttemptSpan annotates and ends the span of a single HTTP attempt.
+func endAttemptSpan(span Span, req *http.Request, attempt int, resp *response, err error) {
+	span.SetAttribute(AttrMethod, req.Method)
+	span.SetAttribute(AttrPath, req.URL.Path)
+	span.SetAttribute(AttrAttempt, attempt)
+	endSpan(span, resp, err)
+}
+
+// endSpan records the outcome of a request on span and ends it.
+func endSpan(span Span, resp *response, err error) {
+	if resp != nil {
+		span.SetAttribute(AttrStatusCode, resp.statusCode)
+		if err == nil && resp.statusCode >= 400 {
+			err = resp.err()
+		}
+	}
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			span.SetAttribute(AttrErrorType, apiErr.Err.Type.String())
+		}
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// RecordingTracer is a Tracer that records spans in memory, meant for
+// use in tests. It is safe for concurrent use.
+type RecordingTracer struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan is a span recorded by a RecordingTracer.
+type RecordedSpan struct {
+	Name       string
+	Context    SpanContext
+	ParentID   [8]byte // Zero for root spans
+	Attributes map[string]interface{}
+	Err        error
+	Start      time.Time
+	End        time.Time // Zero until the span has ended
+
+	mu sync.Mutex
+}
+
+type recordedSpanKey struct{}
+
+// Start is an implementation of the Tracer interface
+func (t *RecordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &RecordedSpan{
+		Name:       name,
+		Attributes: make(map[string]interface{}),
+		Start:      time.Now(),
+	}
+	span.Context.Sampled = true
+	if parent, ok := ctx.Value(recordedSpanKey{}).(*RecordedSpan); ok {
+		span.Context.TraceID = parent.Context.TraceID
+		span.ParentID = parent.Context.SpanID
+	} else {
+		rand.Read(span.Context.TraceID[:])
+	}
+	rand.Read(span.Context.SpanID[:])
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+
+	return context.WithValue(ctx, recordedSpanKey{}, span), recordingSpan{span}
+}
+
+// Spans returns all spans started so far, in the order they were started.
+func (t *RecordingTracer) Spans() []*RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*RecordedSpan(nil), t.spans...)
+}
+
+// recordingSpan implements Span for a RecordedSpan, keeping the methods
+// off the exported RecordedSpan so they do not clash with its fields.
+type recordingSpan struct {
+	s *RecordedSpan
+}
+
+func (r recordingSpan) SetAttribute(key string, value interface{}) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.Attributes[key] = value
+}
+
+func (r recordingSpan) RecordError(err error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.Err = err
+}
+
+func (r recordingSpan) End() {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.End = time.Now()
+}
+
+func (r recordingSpan) SpanContext() SpanContext {
+	return r.s.Context
+}
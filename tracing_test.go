@@ -0,0 +1,189 @@
+package sqwiggle
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test_Tracer_Spans checks that an operation is traced as a span with a
+// child span per attempt, and that the trace context is propagated.
+func Test_Tracer_Spans(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/getmessage.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var traceparents []string
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(dummy)
+	}))
+	defer server.Close()
+
+	tracer := &RecordingTracer{}
+	client := NewClient("test")
+	client.RootURL = server.URL
+	client.Tracer = tracer
+	client.Retry = DefaultRetryPolicy()
+	client.Retry.BaseDelay = time.Millisecond
+
+	_, err = client.GetMessage(3423091)
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("len(spans) = %d, want %d", len(spans), 3)
+	}
+
+	op := spans[0]
+	if op.Name != "GetMessage" {
+		t.Errorf("Name = %q, want %q", op.Name, "GetMessage")
+	}
+	if op.ParentID != [8]byte{} {
+		t.Errorf("operation span has parent %x, want none", op.ParentID)
+	}
+	wantAttrs := map[string]interface{}{
+		AttrOperation:  "GetMessage",
+		AttrResource:   "messages",
+		AttrResourceID: 3423091,
+		AttrStatusCode: 200,
+	}
+	for k, v := range wantAttrs {
+		if op.Attributes[k] != v {
+			t.Errorf("attribute %s = %v, want %v", k, op.Attributes[k], v)
+		}
+	}
+	if op.End.IsZero() {
+		t.Error("operation span was not ended")
+	}
+
+	for i, attempt := range spans[1:] {
+		if attempt.Name != "HTTP GET" {
+			t.Errorf("Name = %q, want %q", attempt.Name, "HTTP GET")
+		}
+		if attempt.ParentID != op.Context.SpanID || attempt.Context.TraceID != op.Context.TraceID {
+			t.Errorf("attempt span %d is not a child of the operation span", i+1)
+		}
+		if attempt.Attributes[AttrAttempt] != i+1 {
+			t.Errorf("attribute %s = %v, want %d", AttrAttempt, attempt.Attributes[AttrAttempt], i+1)
+		}
+		if traceparents[i] != attempt.Context.Traceparent() {
+			t.Errorf("traceparent = %q, want %q", traceparents[i], attempt.Context.Traceparent())
+		}
+	}
+	if spans[1].Err == nil {
+		t.Error("failed attempt span has no error")
+	}
+}
+
+// Test_Tracer_ErrorType checks that API errors are recorded on spans.
+func Test_Tracer_ErrorType(t *testing.T) {
+	dummy, err := ioutil.ReadFile("testdata/error.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, client := setupTestServer(401, dummy, func(r *http.Request) {})
+	defer server.Close()
+
+	tracer := &RecordingTracer{}
+	client.Tracer = tracer
+
+	client.UpdateStream(5, "name")
+
+	spans := tracer.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("len(spans) = %d, want %d", len(spans), 2)
+	}
+	for _, s := range spans {
+		if s.Attributes[AttrErrorType] != "authentication" {
+			t.Errorf("%s: attribute %s = %v, want %q", s.Name, AttrErrorType, s.Attributes[AttrErrorType], "authentication")
+		}
+		if s.Err == nil {
+			t.Errorf("%s: got nil error", s.Name)
+		}
+	}
+}
+
+// Test_Tracer_NestedRequests checks that the requests made by a method
+// that makes several of them are traced under a single operation span.
+func Test_Tracer_NestedRequests(t *testing.T) {
+	server, client, _ := setupLimitsServer(t, 0)
+	defer server.Close()
+	tracer := &RecordingTracer{}
+	client.Tracer = tracer
+
+	if _, err := client.PostStream("new"); err != nil {
+		t.Fatal("got error:", err)
+	}
+
+	// GET /info, GET /streams and POST /streams
+	spans := tracer.Spans()
+	if len(spans) != 4 {
+		t.Fatalf("len(spans) = %d, want %d", len(spans), 4)
+	}
+	op := spans[0]
+	if op.Name != "PostStream" || op.ParentID != [8]byte{} {
+		t.Errorf("first span is %q with parent %x, want the root PostStream span", op.Name, op.ParentID)
+	}
+	if op.Attributes[AttrMethod] != "POST" || op.Attributes[AttrStatusCode] != 201 || op.End.IsZero() {
+		t.Errorf("operation span attributes = %v, want those of the POST", op.Attributes)
+	}
+	want := []string{"HTTP GET", "HTTP GET", "HTTP POST"}
+	for i, s := range spans[1:] {
+		if s.Name != want[i] || s.ParentID != op.Context.SpanID {
+			t.Errorf("span %d is %q with parent %x, want %q as a child of the operation span", i+1, s.Name, s.ParentID, want[i])
+		}
+	}
+
+	// the fallback of ListMessagesWithOptions is traced as one operation
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/streams/1":
+			w.Write([]byte(`{"id": 1}`))
+		case "/messages":
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	tracer = &RecordingTracer{}
+	client = NewClient("test")
+	client.RootURL = server.URL
+	client.Tracer = tracer
+	if _, err := client.ListMessagesWithOptions(0, 0, &ListMessagesOptions{StreamID: 1}); err != nil {
+		t.Fatal("got error:", err)
+	}
+	var roots []string
+	for _, s := range tracer.Spans() {
+		if s.ParentID == [8]byte{} {
+			roots = append(roots, s.Name)
+		}
+	}
+	if len(roots) != 1 || roots[0] != "ListMessagesWithOptions" || len(tracer.Spans()) != 4 {
+		t.Errorf("root spans = %v of %d spans, want only ListMessagesWithOptions with 3 attempts", roots, len(tracer.Spans()))
+	}
+}
+
+func TestSpanContext_Traceparent(t *testing.T) {
+	sc := SpanContext{
+		TraceID: [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		Sampled: true,
+	}
+	want := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	if got := sc.Traceparent(); got != want {
+		t.Errorf("Traceparent() = %q, want %q", got, want)
+	}
+}
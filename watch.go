@@ -0,0 +1,245 @@
+package sqwiggle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMessageInterval = 10 * time.Second // How often a MessageWatcher polls by default
+	defaultMessageWindow   = 100              // How many messages a MessageWatcher tracks by default
+)
+
+// MessageEventType is the kind of change described by a MessageEvent.
+type MessageEventType int
+
+// The below MessageEventType constants define the changes to messages
+// that a MessageWatcher reports.
+const (
+	MessageCreated MessageEventType = iota // A new message was posted
+	MessageUpdated                         // A message was edited
+	MessageDeleted                         // A message was deleted
+)
+
+func (t MessageEventType) String() string {
+	switch t {
+	case MessageCreated:
+		return "created"
+	case MessageUpdated:
+		return "updated"
+	case MessageDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// MessageEvent describes a change to a message found by a MessageWatcher.
+// For MessageDeleted events of messages that can no longer be fetched,
+// only the ID of Message is set.
+type MessageEvent struct {
+	Type    MessageEventType
+	Message Message
+}
+
+// MessageCheckpoint is the state of a MessageWatcher, from which a new
+// watcher can resume without missing events. It can be marshalled to JSON
+// to be stored between runs.
+type MessageCheckpoint struct {
+	LastID        int            `json:"last_id"`         // The ID of the newest message seen
+	LastCreatedAt time.Time      `json:"last_created_at"` // The time the newest message seen was created
+	Recent        []MessageState `json:"recent"`          // The recent messages that are checked for edits and deletions
+}
+
+// MessageState is the state of a message tracked by a MessageWatcher.
+type MessageState struct {
+	ID        int       `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Deleted   bool      `json:"deleted,omitempty"`
+}
+
+// MessageWatcher polls the messages of the organization, and reports new,
+// edited and deleted messages as events. New messages are found with a
+// high-water mark on the message ID. Edits and deletions are found by
+// checking the most recent messages, up to Window of them, for a newer
+// UpdatedAt or the text that DeleteMessage leaves behind.
+//
+// Unless the watcher is resumed from a checkpoint, the first poll only
+// records the current messages, and reports no events.
+type MessageWatcher struct {
+	API      MessagesAPI
+	Interval time.Duration   // How often to poll, defaults to 10 seconds
+	Window   int             // How many recent messages to check for edits and deletions, defaults to 100
+	OnError  func(err error) // Called, if set, when a poll fails; polling continues afterwards
+
+	mu      sync.Mutex
+	started bool
+	state   MessageCheckpoint
+}
+
+// NewMessageWatcher returns a MessageWatcher that polls api. If from is
+// not nil, the watcher resumes from it, and its first poll reports the
+// changes since the checkpoint was taken. A zero checkpoint holds no
+// position to resume from, and is treated like nil, so that the first
+// poll does not report the entire history as new messages.
+func NewMessageWatcher(api MessagesAPI, from *MessageCheckpoint) *MessageWatcher {
+	w := &MessageWatcher{API: api, Interval: defaultMessageInterval, Window: defaultMessageWindow}
+	if from != nil && (from.LastID != 0 || len(from.Recent) != 0) {
+		w.started = true
+		w.state = *from
+		w.state.Recent = append([]MessageState(nil), from.Recent...)
+	}
+	return w
+}
+
+// Checkpoint returns the state of the watcher as of its last poll. While
+// Watch is running, the state only advances once all events of a poll
+// were received, so that resuming from the checkpoint may repeat events,
+// but never misses any. Checkpoint is safe to call concurrently with Watch.
+func (w *MessageWatcher) Checkpoint() MessageCheckpoint {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	cp := w.state
+	cp.Recent = append([]MessageState(nil), w.state.Recent...)
+	return cp
+}
+
+// Watch polls until ctx is done, and sends the events of every poll on
+// the returned channel, which is closed when ctx is done.
+func (w *MessageWatcher) Watch(ctx context.Context) <-chan MessageEvent {
+	events := make(chan MessageEvent)
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultMessageInterval
+	}
+	go func() {
+		defer close(events)
+		for {
+			evs, next, err := w.poll(ctx)
+			if err != nil && ctx.Err() == nil && w.OnError != nil {
+				w.OnError(err)
+			}
+			for _, ev := range evs {
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err == nil {
+				w.commit(next)
+			}
+			if sleep(ctx, interval) != nil {
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// Poll checks for changes once, and returns them ordered by message ID.
+// On error the state of the watcher is left unchanged.
+func (w *MessageWatcher) Poll(ctx context.Context) ([]MessageEvent, error) {
+	events, next, err := w.poll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w.commit(next)
+	return events, nil
+}
+
+// commit makes next the state of the watcher.
+func (w *MessageWatcher) commit(next MessageCheckpoint) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = next
+	w.started = true
+}
+
+// poll returns the changes since the current state, and the state after
+// them.
+func (w *MessageWatcher) poll(ctx context.Context) ([]MessageEvent, MessageCheckpoint, error) {
+	prev := w.Checkpoint()
+	w.mu.Lock()
+	started := w.started
+	w.mu.Unlock()
+
+	tracked := make(map[int]MessageState, len(prev.Recent))
+	oldest := prev.LastID
+	for _, st := range prev.Recent {
+		tracked[st.ID] = st
+		if st.ID < oldest {
+			oldest = st.ID
+		}
+	}
+	window := w.Window
+	if window <= 0 {
+		window = defaultMessageWindow
+	}
+
+	// messages are listed newest first, so stop at the oldest tracked
+	// message, or after a window of messages on the first poll
+	next := MessageCheckpoint{LastID: prev.LastID, LastCreatedAt: prev.LastCreatedAt}
+	var events []MessageEvent
+	seen := make(map[int]bool)
+	n := 0
+	for m, err := range w.API.AllMessages(ctx, nil) {
+		if err != nil {
+			return nil, prev, err
+		}
+		if !started && n >= window {
+			break
+		}
+		n++
+		seen[m.ID] = true
+		if m.ID > next.LastID {
+			next.LastID, next.LastCreatedAt = m.ID, m.CreatedAt
+		}
+		st, ok := tracked[m.ID]
+		switch {
+		case !started:
+		case !ok && m.ID > prev.LastID:
+			events = append(events, MessageEvent{Type: MessageCreated, Message: m})
+		case ok && m.Removed() && !st.Deleted:
+			events = append(events, MessageEvent{Type: MessageDeleted, Message: m})
+		case ok && !m.Removed() && m.UpdatedAt.After(st.UpdatedAt):
+			events = append(events, MessageEvent{Type: MessageUpdated, Message: m})
+		}
+		next.Recent = append(next.Recent, MessageState{ID: m.ID, UpdatedAt: m.UpdatedAt, Deleted: m.Removed()})
+		if started && m.ID <= oldest {
+			break
+		}
+	}
+
+	// tracked messages that were not listed have been removed entirely,
+	// or were missed because the list changed while it was paged through
+	for id, st := range tracked {
+		if seen[id] || st.Deleted {
+			continue
+		}
+		m, err := w.API.GetMessageContext(ctx, id)
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+			events = append(events, MessageEvent{Type: MessageDeleted, Message: Message{ID: id}})
+			continue
+		case err != nil:
+			return nil, prev, err
+		case m.Removed():
+			events = append(events, MessageEvent{Type: MessageDeleted, Message: m})
+		case m.UpdatedAt.After(st.UpdatedAt):
+			events = append(events, MessageEvent{Type: MessageUpdated, Message: m})
+		}
+		next.Recent = append(next.Recent, MessageState{ID: m.ID, UpdatedAt: m.UpdatedAt, Deleted: m.Removed()})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Message.ID < events[j].Message.ID })
+	sort.Slice(next.Recent, func(i, j int) bool { return next.Recent[i].ID > next.Recent[j].ID })
+	if len(next.Recent) > window {
+		next.Recent = next.Recent[:window]
+	}
+	return events, next, nil
+}
@@ -0,0 +1,188 @@
+package sqwiggle
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMessages is a MessagesAPI that only implements AllMessages and
+// GetMessageContext, serving messages from a map.
+type fakeMessages struct {
+	MessagesAPI
+
+	mu   sync.Mutex
+	msgs map[int]Message
+}
+
+func (f *fakeMessages) put(msgs ...Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.msgs == nil {
+		f.msgs = make(map[int]Message)
+	}
+	for _, m := range msgs {
+		f.msgs[m.ID] = m
+	}
+}
+
+func (f *fakeMessages) remove(id int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.msgs, id)
+}
+
+func (f *fakeMessages) AllMessages(ctx context.Context, opts *PageOptions) iter.Seq2[Message, error] {
+	f.mu.Lock()
+	var msgs []Message
+	for _, m := range f.msgs {
+		msgs = append(msgs, m)
+	}
+	f.mu.Unlock()
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].ID > msgs[j].ID })
+	return func(yield func(Message, error) bool) {
+		for _, m := range msgs {
+			if !yield(m, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *fakeMessages) GetMessageContext(ctx context.Context, id int) (Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, ok := f.msgs[id]
+	if !ok {
+		return Message{}, &APIError{StatusCode: http.StatusNotFound}
+	}
+	return m, nil
+}
+
+type eventKey struct {
+	Type MessageEventType
+	ID   int
+}
+
+func messageEvents(events []MessageEvent) []eventKey {
+	keys := []eventKey{}
+	for _, ev := range events {
+		keys = append(keys, eventKey{ev.Type, ev.Message.ID})
+	}
+	return keys
+}
+
+func TestMessageWatcher_Poll(t *testing.T) {
+	t0 := time.Date(2015, time.February, 5, 4, 53, 5, 0, time.UTC)
+	api := &fakeMessages{}
+	for id := 1; id <= 5; id++ {
+		api.put(Message{ID: id, Text: "hi", CreatedAt: t0, UpdatedAt: t0})
+	}
+	w := NewMessageWatcher(api, nil)
+	w.Window = 4
+
+	events, err := w.Poll(context.Background())
+	if err != nil || len(events) != 0 {
+		t.Fatalf("first Poll = %v, %v, want no events", events, err)
+	}
+	if cp := w.Checkpoint(); cp.LastID != 5 || len(cp.Recent) != 4 {
+		t.Errorf("Checkpoint = %+v, want last ID 5 with 4 recent messages", cp)
+	}
+
+	api.put(
+		Message{ID: 6, Text: "new", CreatedAt: t0.Add(time.Minute), UpdatedAt: t0.Add(time.Minute)},
+		Message{ID: 3, Text: "edited", CreatedAt: t0, UpdatedAt: t0.Add(time.Minute)},
+		Message{ID: 4, Text: RemovedText, CreatedAt: t0, UpdatedAt: t0.Add(time.Minute)},
+		Message{ID: 1, Text: "too old to be tracked", CreatedAt: t0, UpdatedAt: t0.Add(time.Minute)},
+	)
+	api.remove(2)
+	events, err = w.Poll(context.Background())
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	want := []eventKey{{MessageDeleted, 2}, {MessageUpdated, 3}, {MessageDeleted, 4}, {MessageCreated, 6}}
+	if got := messageEvents(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("Poll events = %v, want %v", got, want)
+	}
+
+	// nothing changed since
+	if events, _ := w.Poll(context.Background()); len(events) != 0 {
+		t.Errorf("Poll events = %v, want none", messageEvents(events))
+	}
+}
+
+func TestMessageWatcher_Resume(t *testing.T) {
+	t0 := time.Date(2015, time.February, 5, 4, 53, 5, 0, time.UTC)
+	api := &fakeMessages{}
+	api.put(Message{ID: 1, CreatedAt: t0, UpdatedAt: t0}, Message{ID: 2, CreatedAt: t0, UpdatedAt: t0})
+	w := NewMessageWatcher(api, nil)
+	w.Poll(context.Background())
+
+	// the checkpoint survives a round trip through JSON
+	b, err := json.Marshal(w.Checkpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp MessageCheckpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		t.Fatal(err)
+	}
+
+	api.put(Message{ID: 3, CreatedAt: t0, UpdatedAt: t0}, Message{ID: 1, CreatedAt: t0, UpdatedAt: t0.Add(time.Second)})
+	events, err := NewMessageWatcher(api, &cp).Poll(context.Background())
+	if err != nil {
+		t.Fatal("got error:", err)
+	}
+	want := []eventKey{{MessageUpdated, 1}, {MessageCreated, 3}}
+	if got := messageEvents(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("Poll events = %v, want %v", got, want)
+	}
+}
+
+// TestMessageWatcher_ZeroCheckpoint checks that resuming from a zero
+// checkpoint does not report the existing messages as new.
+func TestMessageWatcher_ZeroCheckpoint(t *testing.T) {
+	api := &fakeMessages{}
+	api.put(Message{ID: 1}, Message{ID: 2})
+	w := NewMessageWatcher(api, &MessageCheckpoint{})
+
+	events, err := w.Poll(context.Background())
+	if err != nil || len(events) != 0 {
+		t.Fatalf("first Poll = %v, %v, want no events", messageEvents(events), err)
+	}
+	api.put(Message{ID: 3})
+	events, err = w.Poll(context.Background())
+	if want := []eventKey{{MessageCreated, 3}}; err != nil || !reflect.DeepEqual(messageEvents(events), want) {
+		t.Errorf("Poll = %v, %v, want %v", messageEvents(events), err, want)
+	}
+}
+
+func TestMessageWatcher_Watch(t *testing.T) {
+	api := &fakeMessages{}
+	w := NewMessageWatcher(api, nil)
+	w.Interval = time.Millisecond
+	// record the empty history, so that Watch reports the next message
+	if _, err := w.Poll(context.Background()); err != nil {
+		t.Fatal("got error:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := w.Watch(ctx)
+
+	api.put(Message{ID: 1, Text: "hello"})
+	ev := <-events
+	if ev.Type != MessageCreated || ev.Message.ID != 1 {
+		t.Errorf("got event %v of message %d, want %v of message 1", ev.Type, ev.Message.ID, MessageCreated)
+	}
+
+	cancel()
+	for range events {
+	}
+}